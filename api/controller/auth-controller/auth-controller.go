@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"auth-todo/serviceconfig"
+	"todo-go/pkg/auth"
+	"todo-go/pkg/config"
+	"todo-go/pkg/mongodb"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/ratelimit"
+	"todo-go/pkg/tenant"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// the port is the one the proxy sends the requests to unless it is set
+var svcConfig = serviceconfig.ServiceConfig{HTTP: config.HTTP{Port: 10006}}
+var keys *auth.Keys
+var resolver *tenant.Resolver
+var client *mongo.Client
+var ctx = context.TODO()
+
+// Credentials are the username and password of a user signing up or logging in.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// Refresh is the body of a refresh, trading a refresh token for new tokens.
+type Refresh struct {
+	RefreshToken string
+}
+
+// Tokens are the tokens of a logged in user: the access token is sent as a bearer token to the API
+// until it expires, in ExpiresIn seconds, the refresh token is traded once for new tokens.
+type Tokens struct {
+	AccessToken  string
+	TokenType    string
+	ExpiresIn    int64
+	RefreshToken string
+}
+
+func failOnError(err error, msg string) {
+	if err != nil {
+		log.Fatalf("%s: %s", msg, err)
+	}
+}
+
+func main() {
+	fmt.Printf("Starting the amazing API to authenticate the TODO users\n")
+
+	err := config.Load("auth", &svcConfig)
+	failOnError(err, "There was a problem loading the service configs.")
+
+	keys, err = auth.NewKeys(svcConfig.JwtSecret)
+	failOnError(err, "There was a problem loading the secret of the tokens.")
+
+	resolver, err = tenant.NewResolver(svcConfig.TenantDomain, svcConfig.Tenants)
+	failOnError(err, "There was a problem loading the tenants.")
+
+	limiter, err := ratelimit.New(svcConfig.RateLimit, svcConfig.RateBurst)
+	failOnError(err, "There was a problem loading the rate limit.")
+
+	dummyHash, err = bcrypt.GenerateFromPassword([]byte("not the password of anyone"), svcConfig.BcryptCost)
+	failOnError(err, "There was a problem hashing the passwords.")
+
+	client, err = mongodb.Connect(svcConfig.Database)
+	failOnError(err, "Failed to connect to MongoDB")
+
+	setupApiRouter(limiter)
+}
+
+func setupApiRouter(limiter *ratelimit.Limiter) {
+	router := mux.NewRouter().StrictSlash(true)
+	authRouter := router.PathPrefix("/auth").Subrouter()
+
+	authRouter.Path("/users").Methods(http.MethodPost).HandlerFunc(signUpHandler)
+	authRouter.Path("/login").Methods(http.MethodPost).HandlerFunc(loginHandler)
+	authRouter.Path("/refresh").Methods(http.MethodPost).HandlerFunc(refreshHandler)
+	authRouter.Path("/health").Methods(http.MethodGet).HandlerFunc(healthCheckHandler)
+
+	router.Use(setupLoggingMiddleware)
+	// no user is authenticated yet, the clients are limited by address, which slows down the guessing of the passwords
+	router.Use(limiter.AddressMiddleware("/auth/health"))
+
+	log.Fatal(svcConfig.HTTP.Server(router).ListenAndServe())
+}
+
+func setupLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Do stuff here
+		log.Println(r.RequestURI)
+		log.Println(r.RemoteAddr)
+		// Call the next handler, which can be another middleware in the chain, or the final handler.
+		next.ServeHTTP(w, r)
+	})
+}
+
+func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "Method is not supported.")
+		return
+	}
+
+	fmt.Fprintf(w, "We're good to go.")
+}
+
+// signUpHandler creates a local user of the tenant, when signing up is enabled.
+func signUpHandler(w http.ResponseWriter, r *http.Request) {
+	if !svcConfig.SignupEnabled {
+		problem.Error(w, r, http.StatusForbidden, "Signing up is disabled.")
+		return
+	}
+
+	var credentials Credentials
+	if !decodeBody(w, r, &credentials) {
+		return
+	}
+
+	t, ok := tenantOf(w, r)
+	if !ok {
+		return
+	}
+
+	username := normalizeUsername(credentials.Username)
+
+	err := validateCredentials(username, credentials.Password)
+	if err != nil {
+		problem.Error(w, r, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	user, err := t.createUser(username, credentials.Password)
+	if errors.Is(err, ErrUsernameTaken) {
+		problem.Error(w, r, http.StatusConflict, "The username is taken.")
+		return
+	} else if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(data)
+}
+
+// loginHandler answers the tokens of the user of the tenant of the credentials.
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	var credentials Credentials
+	if !decodeBody(w, r, &credentials) {
+		return
+	}
+
+	t, ok := tenantOf(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := t.authenticate(credentials.Username, credentials.Password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		problem.Error(w, r, http.StatusUnauthorized, "Invalid username or password.")
+		return
+	} else if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	tokensResponse(w, r, t, user)
+}
+
+// refreshHandler trades a refresh token for new tokens, the refresh token can't be used again.
+func refreshHandler(w http.ResponseWriter, r *http.Request) {
+	var refresh Refresh
+	if !decodeBody(w, r, &refresh) {
+		return
+	}
+
+	t, ok := tenantOf(w, r)
+	if !ok {
+		return
+	}
+
+	user, err := t.useRefreshToken(refresh.RefreshToken)
+	if errors.Is(err, ErrInvalidRefreshToken) {
+		problem.Error(w, r, http.StatusUnauthorized, "The refresh token is invalid or expired.")
+		return
+	} else if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	tokensResponse(w, r, t, user)
+}
+
+// tenantOf returns the users of the tenant the request names, answering a 400 when it names an invalid one.
+func tenantOf(w http.ResponseWriter, r *http.Request) (*tenantUsers, bool) {
+	t, err := usersOf(r)
+	if errors.Is(err, tenant.ErrInvalidId) || errors.Is(err, tenant.ErrUnknown) {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return nil, false
+	} else if err != nil {
+		problem.Internal(w, r, err)
+		return nil, false
+	}
+
+	return t, true
+}
+
+// decodeBody reads the JSON body of the request, answering a 400 when it can't.
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	reqBody, _ := ioutil.ReadAll(r.Body)
+
+	err := json.Unmarshal(reqBody, v)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Something went wrong while parsing the JSON from the request body.")
+		return false
+	}
+
+	return true
+}
+
+// tokensResponse answers new tokens of the user of the tenant.
+func tokensResponse(w http.ResponseWriter, r *http.Request, t *tenantUsers, user *User) {
+	accessToken, err := keys.NewToken(user.Id, user.Username, t.id, svcConfig.AccessTokenTTL)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	refreshToken, err := t.newRefreshToken(user.Id)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	data, err := json.Marshal(Tokens{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(svcConfig.AccessTokenTTL.Seconds()),
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	// tokens aren't to be cached
+	w.Header().Add("Cache-Control", "no-store")
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(data)
+}
@@ -0,0 +1,13 @@
+module auth-todo
+
+go 1.16
+
+require (
+	github.com/google/uuid v1.3.0
+	github.com/gorilla/mux v1.8.0
+	go.mongodb.org/mongo-driver v1.3.1
+	golang.org/x/crypto v0.0.0-20190530122614-20be4c3c3ed5
+	todo-go/pkg v0.0.0
+)
+
+replace todo-go/pkg => ../../pkg
@@ -0,0 +1,21 @@
+package serviceconfig
+
+import (
+	"time"
+
+	"todo-go/pkg/config"
+)
+
+type ServiceConfig struct {
+	Database        config.Database
+	HTTP            config.HTTP
+	JwtSecret       string        `required:"true"`
+	AccessTokenTTL  time.Duration `default:"15m"`
+	RefreshTokenTTL time.Duration `default:"720h"`
+	SignupEnabled   bool          `default:"true"` // anyone can sign up on POST /auth/users
+	BcryptCost      int           `default:"12"`
+	TenantDomain    string        // the subdomains of the domain name tenants, e.g. acme.todo.example.com
+	Tenants         []string      // the tenants that can be named besides the default one, * for any tenant
+	RateLimit       float64       `default:"10"` // the requests per second of a user or a client address
+	RateBurst       int           `default:"20"` // the requests they make at once
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"sync"
+
+	"todo-go/pkg/tenant"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// database is the name of the database of the users of the default tenant, the other tenants
+// have their own, see tenant.Database.
+const database = "authDB"
+
+// tenantUsers holds the users of a tenant and their refresh tokens.
+type tenantUsers struct {
+	id            string
+	users         *mongo.Collection
+	refreshTokens *mongo.Collection
+
+	mu      sync.Mutex
+	indexed bool // see createIndexes
+}
+
+// tenants are the users of the tenants used so far.
+var tenants = struct {
+	sync.Mutex
+	all map[string]*tenantUsers
+}{all: make(map[string]*tenantUsers)}
+
+// usersOf returns the users of the tenant the request names, the default tenant when it names none.
+// Reading them doesn't create the database of the tenant.
+func usersOf(r *http.Request) (*tenantUsers, error) {
+	requested, err := resolver.Resolve(r)
+	if err != nil {
+		return nil, err
+	}
+
+	id, _ := tenant.Of(requested)
+
+	tenants.Lock()
+	defer tenants.Unlock()
+
+	t, ok := tenants.all[id]
+	if !ok {
+		db := client.Database(tenant.Database(database, id))
+		t = &tenantUsers{id: id, users: db.Collection("users"), refreshTokens: db.Collection("refreshtokens")}
+		tenants.all[id] = t
+	}
+
+	return t, nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"todo-go/pkg/mongodb"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Lengths of the passwords, bcrypt ignores what comes after 72 bytes.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var usernamePattern = regexp.MustCompile(`^[a-z0-9._-]{3,64}$`)
+
+var ErrUsernameTaken = errors.New("username taken")
+var ErrInvalidCredentials = errors.New("invalid username or password")
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// User is a local user, as stored in the users collection.
+type User struct {
+	Id           string
+	Username     string
+	PasswordHash []byte `json:"-"`
+	CreatedAt    time.Time
+}
+
+// RefreshToken is a refresh token as stored: only its hash is, it is used once and expires.
+type RefreshToken struct {
+	TokenHash string
+	UserId    string
+	ExpiresAt time.Time
+}
+
+// dummyHash is compared to the password of an unknown user, so that logging in takes as long as for a known one.
+var dummyHash []byte
+
+// createIndexes makes the ids and usernames of the tenant unique and expires its refresh tokens. They are created
+// by the first signup to the tenant since the controller started, the requests that don't add a user don't create
+// the database of a tenant. A failure is retried by the next signup.
+func (t *tenantUsers) createIndexes() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.indexed {
+		return nil
+	}
+
+	_, err := t.users.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "username", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = t.refreshTokens.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenhash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expiresat", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return err
+	}
+
+	t.indexed = true
+	return nil
+}
+
+// normalizeUsername returns the username as it is stored, usernames aren't case sensitive.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// validateCredentials returns why the credentials of a new user can't be used, if they can't.
+func validateCredentials(username string, password string) error {
+	if !usernamePattern.MatchString(username) {
+		return errors.New("The username must be 3 to 64 letters, digits, dots, dashes or underscores.")
+	}
+
+	if len(password) < MinPasswordLength || len(password) > MaxPasswordLength {
+		return errors.New("The password must be 8 to 72 bytes long.")
+	}
+
+	return nil
+}
+
+// createUser stores a new user of the tenant with the hash of their password.
+func (t *tenantUsers) createUser(username string, password string) (*User, error) {
+	err := t.createIndexes()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), svcConfig.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	user := User{
+		Id:           uuid.NewString(),
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	_, err = t.users.InsertOne(ctx, user)
+	if mongodb.IsDuplicateKey(err) {
+		return nil, ErrUsernameTaken
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// authenticate returns the user of the tenant of the credentials, an unknown user and a wrong password being told apart by nobody.
+func (t *tenantUsers) authenticate(username string, password string) (*User, error) {
+	var user User
+
+	err := t.users.FindOne(ctx, bson.M{"username": normalizeUsername(username)}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return &user, nil
+}
+
+// newRefreshToken stores a new refresh token of the user and returns it.
+func (t *tenantUsers) newRefreshToken(userId string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	_, err := t.refreshTokens.InsertOne(ctx, RefreshToken{
+		TokenHash: hashToken(token),
+		UserId:    userId,
+		ExpiresAt: time.Now().Add(svcConfig.RefreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// useRefreshToken consumes the refresh token and returns its user, a refresh token is used once.
+func (t *tenantUsers) useRefreshToken(token string) (*User, error) {
+	var used RefreshToken
+
+	filter := bson.M{"tokenhash": hashToken(token), "expiresat": bson.M{"$gt": time.Now()}}
+
+	err := t.refreshTokens.FindOneAndDelete(ctx, filter).Decode(&used)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	var user User
+
+	err = t.users.FindOne(ctx, bson.M{"id": used.UserId}).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrInvalidRefreshToken
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"command-todo/serviceconfig"
+	"command-todo/store"
+	"todo-go/pkg/auth"
+	"todo-go/pkg/config"
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/ratelimit"
+	"todo-go/pkg/tenant"
+
+	"github.com/gorilla/mux"
+	"github.com/streadway/amqp"
+)
+
+var commands *store.CommandStore
+
+func failOnError(err error, msg string) {
+	if err != nil {
+		log.Fatalf("%s: %s", msg, err)
+	}
+}
+
+func main() {
+	fmt.Printf("Starting the amazing API to track TODO commands\n")
+
+	// the port is the one the proxy sends the requests to unless it is set
+	c := serviceconfig.ServiceConfig{HTTP: config.HTTP{Port: 10004}}
+	err := config.Load("command", &c)
+	failOnError(err, "There was a problem loading the service configs.")
+
+	keys, err := auth.NewKeys(c.JwtSecret)
+	failOnError(err, "There was a problem loading the secret of the tokens.")
+
+	tenants, err := tenant.NewResolver(c.TenantDomain, c.Tenants)
+	failOnError(err, "There was a problem loading the tenants.")
+
+	keys.Tenants(tenants)
+
+	limiter, err := ratelimit.New(c.RateLimit, c.RateBurst)
+	failOnError(err, "There was a problem loading the rate limit.")
+
+	commands = store.NewCommandStore(c.CommandTTL)
+
+	replies := messaging.NewConsumer(c.Broker.URL(), c.InboundQueueName, c.ConsumerName, handleReply).
+		TLS(c.Broker.TLS.Config())
+
+	go replies.ListenAndServe()
+	go evictCommands(c.CommandTTL)
+
+	setupApiRouter(c.HTTP, keys, limiter)
+}
+
+func setupApiRouter(server config.HTTP, keys *auth.Keys, limiter *ratelimit.Limiter) {
+	router := mux.NewRouter().StrictSlash(true)
+	commandRouter := router.PathPrefix("/todo").Methods(http.MethodGet).Subrouter()
+
+	commandRouter.Path("/command/health").HandlerFunc(healthCheckHandler)
+	commandRouter.Path("/commands/{correlationId}").HandlerFunc(retrieveCommandHandler)
+
+	router.Use(setupLoggingMiddleware)
+	router.Use(limiter.AddressMiddleware("/todo/command/health"))
+	router.Use(keys.Middleware("/todo/command/health"))
+	router.Use(limiter.Middleware("/todo/command/health"))
+
+	log.Fatal(server.Server(router).ListenAndServe())
+}
+
+func setupLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Do stuff here
+		log.Println(r.RequestURI)
+		log.Println(r.RemoteAddr)
+		// Call the next handler, which can be another middleware in the chain, or the final handler.
+		next.ServeHTTP(w, r)
+	})
+}
+
+func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "Method is not supported.")
+		return
+	}
+
+	fmt.Fprintf(w, "We're good to go.")
+}
+
+func retrieveCommandHandler(w http.ResponseWriter, r *http.Request) {
+	variables := mux.Vars(r)
+
+	// the commands of the other users are as unknown as the ones that don't exist
+	cmd, ok := commands.Get(variables["correlationId"])
+	if !ok || cmd.UserId != auth.UserId(r.Context()) || cmd.TenantId != tenant.Id(r.Context()) {
+		problem.Error(w, r, http.StatusNotFound, "Unknown command, or its status expired.")
+		return
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(data)
+}
+
+// handleReply registers the command the controllers accepted, or completes it with the reply of the DAO.
+func handleReply(d amqp.Delivery) {
+	if d.CorrelationId == "" {
+		log.Println("Discarding message without correlation id")
+		return
+	}
+
+	if d.Type == messaging.AcceptedType {
+		userId, _ := d.Headers[messaging.UserIdHeader].(string)
+		tenantId, _ := d.Headers[messaging.TenantHeader].(string)
+		if tenantId == "" {
+			tenantId = tenant.Default
+		}
+
+		commands.Accept(d.CorrelationId, string(d.Body), tenantId, userId)
+		return
+	}
+
+	instance := "/todo/commands/" + d.CorrelationId
+
+	var data messaging.Result
+	err := json.Unmarshal(d.Body, &data)
+
+	if err != nil {
+		log.Println("Failed to parse the message returned by the DAO:", err)
+		failure := problem.New(http.StatusBadGateway, "Invalid reply from the DAO.")
+		failure.Instance = instance
+		commands.Complete(d.CorrelationId, failure, nil)
+		return
+	}
+
+	var failure *problem.Problem
+	if data.Err != nil {
+		failure = problem.FromError(data.Err)
+		failure.Instance = instance
+	}
+
+	commands.Complete(d.CorrelationId, failure, []byte(data.Result))
+}
+
+func evictCommands(ttl time.Duration) {
+	for range time.Tick(ttl / 2) {
+		commands.Evict()
+	}
+}
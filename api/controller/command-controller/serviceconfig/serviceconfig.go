@@ -0,0 +1,20 @@
+package serviceconfig
+
+import (
+	"time"
+
+	"todo-go/pkg/config"
+)
+
+type ServiceConfig struct {
+	InboundQueueName string `required:"true"`
+	Broker           config.Broker
+	HTTP             config.HTTP
+	ConsumerName     string        `default:"command-todo"`
+	CommandTTL       time.Duration `default:"1h"`
+	JwtSecret        string        `required:"true"`
+	TenantDomain     string        // the subdomains of the domain name tenants, e.g. acme.todo.example.com
+	Tenants          []string      // the tenants that can be named besides the default one, * for any tenant
+	RateLimit        float64       `default:"10"` // the requests per second of a user or a client address
+	RateBurst        int           `default:"20"` // the requests they make at once
+}
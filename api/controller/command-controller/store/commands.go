@@ -0,0 +1,111 @@
+package store
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"todo-go/pkg/problem"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+type Command struct {
+	CorrelationId string
+	Operation     string `json:",omitempty"`
+	Status        string
+	Err           *problem.Problem `json:",omitempty"`
+	Result        json.RawMessage  `json:",omitempty"` // resulting todo, or list, in json
+	UpdatedAt     time.Time
+	UserId        string `json:"-"` // user who sent the command, the only one seeing its status
+	TenantId      string `json:"-"` // tenant of the user
+}
+
+// CommandStore keeps the last known status of every command submitted by the controllers.
+// Entries older than the configured ttl are dropped by Evict. They only live in the memory of the process,
+// lost when it restarts and unknown to the other replicas.
+type CommandStore struct {
+	mu       sync.RWMutex
+	commands map[string]*Command
+	ttl      time.Duration
+}
+
+func NewCommandStore(ttl time.Duration) *CommandStore {
+	return &CommandStore{
+		commands: make(map[string]*Command),
+		ttl:      ttl,
+	}
+}
+
+// Accept registers a command sent by the user of the tenant as pending. A command whose result already arrived is left untouched.
+func (s *CommandStore) Accept(correlationId string, operation string, tenantId string, userId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cmd, ok := s.commands[correlationId]; ok {
+		cmd.Operation = operation
+		cmd.UserId = userId
+		cmd.TenantId = tenantId
+		return
+	}
+
+	s.commands[correlationId] = &Command{
+		CorrelationId: correlationId,
+		Operation:     operation,
+		Status:        StatusPending,
+		UpdatedAt:     time.Now(),
+		UserId:        userId,
+		TenantId:      tenantId,
+	}
+}
+
+// Complete records the result replied by a DAO for the given command, failure being the DAO error if any.
+func (s *CommandStore) Complete(correlationId string, failure *problem.Problem, result []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cmd, ok := s.commands[correlationId]
+	if !ok {
+		cmd = &Command{CorrelationId: correlationId}
+		s.commands[correlationId] = cmd
+	}
+
+	cmd.Status = StatusSucceeded
+	cmd.Err = failure
+	cmd.Result = nil
+	cmd.UpdatedAt = time.Now()
+
+	if failure != nil {
+		cmd.Status = StatusFailed
+	} else if json.Valid(result) {
+		cmd.Result = result
+	}
+}
+
+func (s *CommandStore) Get(correlationId string) (Command, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	cmd, ok := s.commands[correlationId]
+	if !ok {
+		return Command{}, false
+	}
+
+	return *cmd, true
+}
+
+func (s *CommandStore) Evict() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := time.Now().Add(-s.ttl)
+	for id, cmd := range s.commands {
+		if cmd.UpdatedAt.Before(limit) {
+			delete(s.commands, id)
+		}
+	}
+}
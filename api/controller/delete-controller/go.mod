@@ -4,5 +4,7 @@ go 1.16
 
 require (
 	github.com/gorilla/mux v1.8.0
-	github.com/streadway/amqp v1.0.0
+	todo-go/pkg v0.0.0
 )
+
+replace todo-go/pkg => ../../pkg
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"todo-go/pkg/auth"
+	"todo-go/pkg/etag"
+	"todo-go/pkg/problem"
+
+	"github.com/gorilla/mux"
+)
+
+// Item is the command sent to delete-dao for an item of a todo.
+type Item struct {
+	TodoId  string
+	ItemId  string
+	IfMatch []int64 `json:",omitempty"` // versions of the todo the item can be removed from
+}
+
+func deleteItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodDelete {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "Method is not supported.")
+		return
+	}
+
+	var dadosJson Item
+
+	variables := mux.Vars(r)
+	dadosJson.TodoId = variables["id"]
+	dadosJson.ItemId = variables["itemId"]
+
+	ifMatch, err := etag.IfMatch(r.Header.Get("If-Match"))
+	if errors.Is(err, etag.ErrNoMatch) {
+		problem.Error(w, r, http.StatusPreconditionFailed, err.Error())
+		return
+	} else if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dadosJson.IfMatch = ifMatch
+
+	itemBytes, err := json.Marshal(dadosJson)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	corrId, err := rabbit.SendCommand(svcConfig.ItemOutboundQueueName, svcConfig.CommandQueueName, "delete-item", itemBytes, auth.Headers(r.Context()))
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	acceptedResponse(w, r, corrId)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"todo-go/pkg/auth"
+	"todo-go/pkg/etag"
+	"todo-go/pkg/problem"
+
+	"github.com/gorilla/mux"
+)
+
+// List is the command sent to delete-dao for a list, its todos are moved to the trash along with it.
+type List struct {
+	Id      string
+	IfMatch []int64 `json:",omitempty"` // versions of the list that can be deleted
+}
+
+func deleteListHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodDelete {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "Method is not supported.")
+		return
+	}
+
+	var dadosJson List
+
+	variables := mux.Vars(r)
+	dadosJson.Id = variables["listId"]
+
+	ifMatch, err := etag.IfMatch(r.Header.Get("If-Match"))
+	if errors.Is(err, etag.ErrNoMatch) {
+		problem.Error(w, r, http.StatusPreconditionFailed, err.Error())
+		return
+	} else if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dadosJson.IfMatch = ifMatch
+
+	listBytes, err := json.Marshal(dadosJson)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	corrId, err := rabbit.SendCommand(svcConfig.ListOutboundQueueName, svcConfig.CommandQueueName, "delete-list", listBytes, auth.Headers(r.Context()))
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	acceptedResponse(w, r, corrId)
+}
@@ -1,50 +1,88 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"net/http"
-
-	config "get-todo/serviceconfig"
-	services "get-todo/services"
+	"net/url"
+	"strconv"
+	"time"
+
+	"get-todo/serviceconfig"
+	"todo-go/pkg/auth"
+	"todo-go/pkg/config"
+	"todo-go/pkg/etag"
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/ratelimit"
+	"todo-go/pkg/tenant"
+	"todo-go/pkg/todo"
 
 	"github.com/gorilla/mux"
-	"github.com/kelseyhightower/envconfig"
 )
 
-type Result struct {
-	Err    string
-	Result string // result in json
-}
-
-type Todo struct {
-	Id   string
-	Text string
-	Done bool
-}
-
-func (todo *Todo) isEmpty() bool {
-	return todo.Id == "" && todo.Text == ""
-}
+// the port is the one the proxy sends the requests to unless it is set
+var svcConfig = serviceconfig.ServiceConfig{HTTP: config.HTTP{Port: 10001}}
+var rabbit *messaging.Client
 
 func main() {
 	fmt.Printf("Starting the amazing API to get TODOs\n")
+
+	err := config.Load("get", &svcConfig)
+	if err != nil {
+		log.Fatalf("There was a problem loading the service configs: %s", err)
+	}
+
+	rabbit = messaging.NewClient(svcConfig.Broker.URL(), svcConfig.RequestTimeout, svcConfig.ChannelPoolSize).
+		TLS(svcConfig.Broker.TLS.Config())
+
 	setupApiRouter()
 }
 
 func setupApiRouter() {
+	keys, err := auth.NewKeys(svcConfig.JwtSecret)
+	if err != nil {
+		log.Fatalf("There was a problem loading the secret of the tokens: %s", err)
+	}
+
+	tenants, err := tenant.NewResolver(svcConfig.TenantDomain, svcConfig.Tenants)
+	if err != nil {
+		log.Fatalf("There was a problem loading the tenants: %s", err)
+	}
+
+	keys.Tenants(tenants)
+
+	limiter, err := ratelimit.New(svcConfig.RateLimit, svcConfig.RateBurst)
+	if err != nil {
+		log.Fatalf("There was a problem loading the rate limit: %s", err)
+	}
+
 	router := mux.NewRouter().StrictSlash(true)
 	getTodoRouter := router.PathPrefix("/todo").Methods(http.MethodGet).Subrouter()
 
 	getTodoRouter.Path("/").HandlerFunc(listTodosHandler)
+	getTodoRouter.Path("/occurrences").HandlerFunc(listOccurrencesHandler)
+	getTodoRouter.Path("/trash").HandlerFunc(listTrashHandler)
 	getTodoRouter.Path("/{id}").HandlerFunc(retrieveTodoHandler)
+	getTodoRouter.Path("/{id}/items").HandlerFunc(retrieveItemsHandler)
+	getTodoRouter.Path("/{id}/history").HandlerFunc(retrieveHistoryHandler)
 	getTodoRouter.Path("/get/health").HandlerFunc(healthCheckHandler)
 
+	listsRouter := router.PathPrefix("/lists").Methods(http.MethodGet).Subrouter()
+
+	listsRouter.Path("/").HandlerFunc(listListsHandler)
+	listsRouter.Path("/{listId}").HandlerFunc(retrieveListHandler)
+	listsRouter.Path("/{listId}/todos").HandlerFunc(listTodosHandler)
+
 	router.Use(setupLoggingMiddleware)
+	router.Use(limiter.AddressMiddleware("/todo/get/health"))
+	router.Use(keys.Middleware("/todo/get/health"))
+	router.Use(limiter.Middleware("/todo/get/health"))
 
-	log.Fatal(http.ListenAndServe(":10001", router))
+	log.Fatal(svcConfig.HTTP.Server(router).ListenAndServe())
 }
 
 func setupLoggingMiddleware(next http.Handler) http.Handler {
@@ -62,103 +100,207 @@ func formatJsonResponse(w http.ResponseWriter, data []byte) {
 	w.Write(data)
 }
 
-func validateResponse(data []byte) bool {
+// errorResponse answers with the problem matching err, the DAO errors keeping the status of their code.
+func errorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, context.Canceled) {
+		// the client went away, nobody is left to read the response
+		log.Println("Request cancelled by the client:", r.RequestURI)
+		return
+	}
+
+	var timeoutErr *messaging.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		w.Header().Add("X-Correlation-Id", timeoutErr.CorrelationId)
+	}
+
+	problem.FromError(err).Write(w, r)
+}
+
+// TodoQuery is the request sent to get-dao: a single todo when Id is set, a page of the todos otherwise.
+type TodoQuery struct {
+	Id       string `json:",omitempty"`
+	ListId   string `json:",omitempty"`
+	Done     *bool  `json:",omitempty"`
+	Q        string `json:",omitempty"`
+	Tag      string `json:",omitempty"`
+	Priority string `json:",omitempty"`
+	Sort     string `json:",omitempty"`
+	Limit    int    `json:",omitempty"`
+	Cursor   string `json:",omitempty"`
+	Trashed  bool   `json:",omitempty"`
+	History  bool   `json:",omitempty"`
+
+	From *time.Time `json:",omitempty"`
+	To   *time.Time `json:",omitempty"`
+}
+
+// TodoPage is a page of the todos replied by get-dao.
+type TodoPage struct {
+	Todos      json.RawMessage
+	NextCursor string
+}
+
+// parseListQuery reads the ?done=&q=&tag=&priority=&sort=&limit=&cursor= parameters of a listing.
+func parseListQuery(r *http.Request) (*TodoQuery, error) {
+	params := r.URL.Query()
+
+	query := TodoQuery{
+		Q:        params.Get("q"),
+		Tag:      params.Get("tag"),
+		Priority: params.Get("priority"),
+		Sort:     params.Get("sort"),
+		Cursor:   params.Get("cursor"),
+	}
+
+	if done := params.Get("done"); done != "" {
+		value, err := strconv.ParseBool(done)
+		if err != nil {
+			return nil, fmt.Errorf("invalid done parameter %q", done)
+		}
+
+		query.Done = &value
+	}
+
+	if query.Priority != "" && !todo.IsPriority(query.Priority) {
+		return nil, fmt.Errorf("invalid priority parameter %q", query.Priority)
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid limit parameter %q", limit)
+		}
 
-	var todoJson Todo
-	json.Unmarshal(data, &todoJson)
+		query.Limit = value
+	}
 
-	return !todoJson.isEmpty()
+	return &query, nil
 }
 
+// listTodosHandler answers a page of the todos, or of the todos of a list on /lists/{listId}/todos,
+// with a Link to the next one unless it is the last.
 func listTodosHandler(w http.ResponseWriter, r *http.Request) {
-	data, err := connectAndSend([]byte("0"))
+	query, err := parseListQuery(r)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	query.ListId = mux.Vars(r)["listId"]
 
+	sendListQuery(w, r, query)
+}
+
+// listTrashHandler answers a page of the todos in the trash, the ones deleted last first unless sorted otherwise.
+func listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	formatJsonResponse(w, data)
+	query.Trashed = true
+
+	sendListQuery(w, r, query)
 }
 
-func retrieveTodoHandler(w http.ResponseWriter, r *http.Request) {
-	variables := mux.Vars(r)
-	data, err := connectAndSend([]byte(variables["id"]))
+// sendListQuery answers the page of the todos get-dao returns for the query.
+func sendListQuery(w http.ResponseWriter, r *http.Request, query *TodoQuery) {
+	data, err := connectAndSend(r.Context(), svcConfig.OutboundQueueName, query)
+
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
 
+	var page TodoPage
+	err = json.Unmarshal(data, &page)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		errorResponse(w, r, fmt.Errorf("failed to parse the todos returned by the DAL: %w", err))
 		return
 	}
 
-	if validateResponse(data) {
-		formatJsonResponse(w, data)
-	} else {
-		http.Error(w, "Not Found", http.StatusNotFound)
+	if page.NextCursor != "" {
+		params := r.URL.Query()
+		params.Set("cursor", page.NextCursor)
+
+		next := url.URL{Path: r.URL.Path, RawQuery: params.Encode()}
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.String()))
 	}
+
+	formatJsonResponse(w, page.Todos)
 }
 
-func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+// TodoVersion is the part of a todo, or of a list, its entity tag is made of.
+type TodoVersion struct {
+	Version int64
+}
 
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method is not supported.", http.StatusMethodNotAllowed)
+func retrieveTodoHandler(w http.ResponseWriter, r *http.Request) {
+	variables := mux.Vars(r)
+	data, err := connectAndSend(r.Context(), svcConfig.OutboundQueueName, &TodoQuery{Id: variables["id"]})
+
+	if err != nil {
+		errorResponse(w, r, err)
 		return
 	}
 
-	fmt.Fprintf(w, "We're good to go.")
+	versionedResponse(w, r, data)
 }
 
-func connectAndSend(id []byte) (res []byte, err error) {
-
-	// move to main init or.... main methods/ and inject them here.
-	var c config.ServiceConfig
-	err = envconfig.Process("get", &c)
+// versionedResponse answers the todo or the list returned by the DAO with its ETag,
+// or 304 when it matches If-None-Match.
+func versionedResponse(w http.ResponseWriter, r *http.Request, data []byte) {
+	var current TodoVersion
+	err := json.Unmarshal(data, &current)
 	if err != nil {
-		return nil, fmt.Errorf("there was a problem loading the service configs: %w", err)
+		errorResponse(w, r, fmt.Errorf("failed to parse the result returned by the DAL: %w", err))
+		return
 	}
 
-	corrId := randomString(32)
+	writeVersioned(w, r, current.Version, data)
+}
 
-	rabbit := &services.RabbitQueue{Config: c}
-	consumer, err := rabbit.PublishAndListen(id, corrId)
+// writeVersioned answers body with the ETag of the version, or 304 when it matches If-None-Match.
+func writeVersioned(w http.ResponseWriter, r *http.Request, version int64, body []byte) {
+	w.Header().Set("ETag", etag.Format(version))
 
+	ifNoneMatch, err := etag.Parse(r.Header.Get("If-None-Match"))
 	if err != nil {
-		return nil, fmt.Errorf("there was a problem sending a request to the DAL: %w", err)
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	defer rabbit.Close()
+	if ifNoneMatch != nil && ifNoneMatch.Matches(version) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 
-	for d := range consumer {
-		if corrId == d.CorrelationId {
-			fmt.Println("Message received from DAO")
-			fmt.Printf("%#v \n", d)
+	formatJsonResponse(w, body)
+}
 
-			var data *Result
-			err := json.Unmarshal(d.Body, &data)
+func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 
-			if err != nil {
-				return nil, fmt.Errorf("failed to parse the message returned by the DAO: %w", err)
-			}
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "Method is not supported.")
+		return
+	}
 
-			if data.Err != "" {
-				return nil, fmt.Errorf("DAO error: %w", data.Err)
-			}
+	fmt.Fprintf(w, "We're good to go.")
+}
 
-			res = []byte(data.Result)
-			break
-		}
+// connectAndSend sends the query to the DAO queue on behalf of the user of the context and waits for its reply.
+func connectAndSend(ctx context.Context, queue string, query interface{}) (res []byte, err error) {
+	body, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
 	}
 
-	return
-}
+	res, err = rabbit.Call(ctx, queue, body, auth.Headers(ctx))
 
-func randomString(l int) string {
-	bytes := make([]byte, l)
-	for i := 0; i < l; i++ {
-		bytes[i] = byte(randInt(65, 90))
+	if err != nil {
+		return nil, fmt.Errorf("there was a problem sending a request to the DAL: %w", err)
 	}
-	return string(bytes)
-}
 
-func randInt(min int, max int) int {
-	return min + rand.Intn(max-min)
+	return res, nil
 }
@@ -4,6 +4,16 @@ go 1.17
 
 require (
 	github.com/gorilla/mux v1.8.0
-	github.com/kelseyhightower/envconfig v1.4.0
-	github.com/streadway/amqp v1.0.0
+	todo-go/pkg v0.0.0
 )
+
+require (
+	github.com/golang-jwt/jwt/v4 v4.5.2 // indirect
+	github.com/google/uuid v1.3.0 // indirect
+	github.com/kelseyhightower/envconfig v1.4.0 // indirect
+	github.com/streadway/amqp v1.0.0 // indirect
+	github.com/teambition/rrule-go v1.8.2 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+replace todo-go/pkg => ../../pkg
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"todo-go/pkg/problem"
+
+	"github.com/gorilla/mux"
+)
+
+// retrieveHistoryHandler answers the last changes of a todo, the last one first, at most ?limit= of them.
+func retrieveHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	query := TodoQuery{Id: mux.Vars(r)["id"], History: true}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value <= 0 {
+			problem.Error(w, r, http.StatusBadRequest, fmt.Sprintf("invalid limit parameter %q", limit))
+			return
+		}
+
+		query.Limit = value
+	}
+
+	data, err := connectAndSend(r.Context(), svcConfig.OutboundQueueName, &query)
+
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	formatJsonResponse(w, data)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// TodoItems is the part of a todo answered on /todo/{id}/items, the items share the ETag of their todo.
+type TodoItems struct {
+	Version int64
+	Items   []json.RawMessage
+}
+
+// retrieveItemsHandler answers the checklist of a todo, in its order.
+func retrieveItemsHandler(w http.ResponseWriter, r *http.Request) {
+	variables := mux.Vars(r)
+	data, err := connectAndSend(r.Context(), svcConfig.OutboundQueueName, &TodoQuery{Id: variables["id"]})
+
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	var current TodoItems
+	err = json.Unmarshal(data, &current)
+	if err != nil {
+		errorResponse(w, r, fmt.Errorf("failed to parse the todo returned by the DAL: %w", err))
+		return
+	}
+
+	if current.Items == nil {
+		current.Items = []json.RawMessage{}
+	}
+
+	items, err := json.Marshal(current.Items)
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	writeVersioned(w, r, current.Version, items)
+}
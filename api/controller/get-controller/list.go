@@ -0,0 +1,36 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// ListQuery is the request sent to get-dao for the lists: a single list when Id is set, all of them otherwise.
+type ListQuery struct {
+	Id string `json:",omitempty"`
+}
+
+// listListsHandler answers all the lists, sorted by name.
+func listListsHandler(w http.ResponseWriter, r *http.Request) {
+	data, err := connectAndSend(r.Context(), svcConfig.ListOutboundQueueName, &ListQuery{})
+
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	formatJsonResponse(w, data)
+}
+
+func retrieveListHandler(w http.ResponseWriter, r *http.Request) {
+	variables := mux.Vars(r)
+	data, err := connectAndSend(r.Context(), svcConfig.ListOutboundQueueName, &ListQuery{Id: variables["listId"]})
+
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	versionedResponse(w, r, data)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"todo-go/pkg/problem"
+)
+
+// listOccurrencesHandler answers the occurrences of the pending todos due in the ?from=&to= window,
+// the recurring todos being repeated in it. They can be filtered like the todos but are always sorted by due date.
+func listOccurrencesHandler(w http.ResponseWriter, r *http.Request) {
+	query, err := parseListQuery(r)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if query.Done != nil || query.Sort != "" || query.Cursor != "" {
+		problem.Error(w, r, http.StatusBadRequest, "The occurrences are the pending ones sorted by due date, in a single page.")
+		return
+	}
+
+	query.From, err = parseTime(r, "from")
+	if err == nil {
+		query.To, err = parseTime(r, "to")
+	}
+
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	data, err := connectAndSend(r.Context(), svcConfig.OutboundQueueName, query)
+
+	if err != nil {
+		errorResponse(w, r, err)
+		return
+	}
+
+	formatJsonResponse(w, data)
+}
+
+// parseTime reads the RFC 3339 date of a required query parameter.
+func parseTime(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s parameter %q, it is an RFC 3339 date", name, value)
+	}
+
+	return &t, nil
+}
@@ -1,17 +1,21 @@
 package serviceconfig
 
-import "fmt"
+import (
+	"time"
 
-type ServiceConfig struct {
-	InboundQueueName  string `required:"true"`
-	OutboundQueueName string `required:"true"`
-	RabbitMqServer    string `required:"true"`
-	RabbitMqPort      string `default:"5672"`
-	RabbitMqUser      string `required:"true"`
-	RabbitMqPswd      string `required:"true"`
-	ConsumerName      string `default:"get-todo"`
-}
+	"todo-go/pkg/config"
+)
 
-func (c *ServiceConfig) GetRabbitConnString() string {
-	return fmt.Sprintf("amqp://%s:%s@%s:%s/", c.RabbitMqUser, c.RabbitMqPswd, c.RabbitMqServer, c.RabbitMqPort)
+type ServiceConfig struct {
+	OutboundQueueName     string `required:"true"`
+	ListOutboundQueueName string `required:"true"`
+	Broker                config.Broker
+	HTTP                  config.HTTP
+	RequestTimeout        time.Duration `default:"10s"`
+	ChannelPoolSize       int           `default:"8"`
+	JwtSecret             string        `required:"true"`
+	TenantDomain          string        // the subdomains of the domain name tenants, e.g. acme.todo.example.com
+	Tenants               []string      // the tenants that can be named besides the default one, * for any tenant
+	RateLimit             float64       `default:"10"` // the requests per second of a user or a client address
+	RateBurst             int           `default:"20"` // the requests they make at once
 }
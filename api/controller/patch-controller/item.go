@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"todo-go/pkg/problem"
+	"todo-go/pkg/todo"
+
+	"github.com/gorilla/mux"
+)
+
+// ItemPatch is the command sent to patch-dao for an item of a todo: the fields to set, and the
+// position it is moved to if any, applied only if the version of the todo is one of IfMatch.
+type ItemPatch struct {
+	TodoId   string
+	ItemId   string
+	Set      map[string]interface{} `json:",omitempty"`
+	Position *int                   `json:",omitempty"`
+	IfMatch  []int64                `json:",omitempty"`
+}
+
+func (p *ItemPatch) ifMatch(versions []int64) {
+	p.IfMatch = versions
+}
+
+// parseItemPatch reads a RFC 7396 merge patch of an item: its text, whether it is done and its
+// position in the checklist. None of them can be removed.
+func parseItemPatch(todoId string, itemId string, body []byte) (*ItemPatch, error) {
+	var members map[string]json.RawMessage
+
+	err := json.Unmarshal(body, &members)
+	if err != nil || members == nil {
+		return nil, fmt.Errorf("%w: a merge patch is a json object", errMalformedPatch)
+	}
+
+	patch := &ItemPatch{TodoId: todoId, ItemId: itemId, Set: make(map[string]interface{})}
+
+	for member, raw := range members {
+		field := fieldName(member)
+
+		if field == todo.FieldPosition {
+			var position int
+			if json.Unmarshal(raw, &position) != nil {
+				return nil, fmt.Errorf("%w: the position of an item is a number", errMalformedPatch)
+			}
+
+			err = todo.ValidatePosition(position)
+			if err != nil {
+				return nil, err
+			}
+
+			patch.Position = &position
+			continue
+		}
+
+		value, err := todo.DecodeItemField(field, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		patch.Set[field] = value
+	}
+
+	return patch, nil
+}
+
+// updateItemHandler changes, completes or moves an item of a todo, only merge patches are supported.
+func updateItemHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPatch {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "Method is not supported.")
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != MergePatchType && mediaType != "application/json" && mediaType != "" {
+		w.Header().Set("Accept-Patch", MergePatchType)
+		problem.Error(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported patch document %q.", mediaType))
+		return
+	}
+
+	reqBody, _ := ioutil.ReadAll(r.Body)
+
+	variables := mux.Vars(r)
+
+	patch, err := parseItemPatch(variables["id"], variables["itemId"], reqBody)
+
+	sendPatch(w, r, svcConfig.ItemOutboundQueueName, "patch-item", patch, err)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/todo"
+
+	"github.com/gorilla/mux"
+)
+
+// ListPatch is the command sent to patch-dao for a list: the fields to set and to remove,
+// applied only if its version is one of IfMatch.
+type ListPatch struct {
+	Id      string
+	Set     map[string]interface{} `json:",omitempty"`
+	Unset   []string               `json:",omitempty"`
+	IfMatch []int64                `json:",omitempty"`
+}
+
+func (p *ListPatch) ifMatch(versions []int64) {
+	p.IfMatch = versions
+}
+
+// parseListPatch reads a RFC 7396 merge patch of a list: every member sets its field, a null member removes it.
+func parseListPatch(id string, body []byte) (*ListPatch, error) {
+	var members map[string]json.RawMessage
+
+	err := json.Unmarshal(body, &members)
+	if err != nil || members == nil {
+		return nil, fmt.Errorf("%w: a merge patch is a json object", errMalformedPatch)
+	}
+
+	patch := &ListPatch{Id: id, Set: make(map[string]interface{})}
+
+	for member, raw := range members {
+		field := fieldName(member)
+
+		if string(raw) == "null" {
+			if !todo.ListRemovable(field) {
+				return nil, messaging.Invalid("the field %q can't be removed", field).WithDetail("field", field)
+			}
+
+			patch.Unset = append(patch.Unset, field)
+			continue
+		}
+
+		value, err := todo.DecodeListField(field, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		patch.Set[field] = value
+	}
+
+	return patch, nil
+}
+
+// updateListHandler changes the name, the description or the shares of a list, only merge patches are supported.
+func updateListHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodPatch {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "Method is not supported.")
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != MergePatchType && mediaType != "application/json" && mediaType != "" {
+		w.Header().Set("Accept-Patch", MergePatchType)
+		problem.Error(w, r, http.StatusUnsupportedMediaType, fmt.Sprintf("Unsupported patch document %q.", mediaType))
+		return
+	}
+
+	reqBody, _ := ioutil.ReadAll(r.Body)
+
+	variables := mux.Vars(r)
+
+	patch, err := parseListPatch(variables["listId"], reqBody)
+
+	sendPatch(w, r, svcConfig.ListOutboundQueueName, "patch-list", patch, err)
+}
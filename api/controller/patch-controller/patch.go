@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/todo"
+)
+
+// Content types of the patch documents accepted by PATCH /todo/{id}. A plain json body is read as a merge patch.
+const (
+	MergePatchType = "application/merge-patch+json"
+	JSONPatchType  = "application/json-patch+json"
+)
+
+var errMalformedPatch = errors.New("malformed patch document")
+
+// TodoPatch is the command sent to patch-dao: the fields to set and to remove, applied only if
+// the fields in Test have the given values, the ones in Exists are set and its version is one of
+// IfMatch. The todo is created when Upsert is set, and only created when CreateOnly is set as well.
+type TodoPatch struct {
+	Id         string
+	Set        map[string]interface{} `json:",omitempty"`
+	Unset      []string               `json:",omitempty"`
+	Test       map[string]interface{} `json:",omitempty"`
+	Exists     []string               `json:",omitempty"`
+	IfMatch    []int64                `json:",omitempty"`
+	Upsert     bool                   `json:",omitempty"`
+	CreateOnly bool                   `json:",omitempty"`
+}
+
+func newTodoPatch(id string) *TodoPatch {
+	return &TodoPatch{
+		Id:   id,
+		Set:  make(map[string]interface{}),
+		Test: make(map[string]interface{}),
+	}
+}
+
+func (p *TodoPatch) ifMatch(versions []int64) {
+	p.IfMatch = versions
+}
+
+func (p *TodoPatch) set(field string, value interface{}) {
+	p.forgetUnset(field)
+	p.Set[field] = value
+}
+
+func (p *TodoPatch) unset(field string) error {
+	if !todo.Removable(field) {
+		return messaging.Invalid("the field %q can't be removed", field).WithDetail("field", field)
+	}
+
+	delete(p.Set, field)
+	p.forgetUnset(field)
+	p.Unset = append(p.Unset, field)
+	return nil
+}
+
+// remove unsets a field the way a json patch does: the field has to be set, by the patch
+// or else in the stored todo.
+func (p *TodoPatch) remove(field string) error {
+	if contains(p.Unset, field) {
+		return messaging.Invalid("the field %q is already removed by the patch", field).WithDetail("field", field)
+	}
+
+	if _, ok := p.Set[field]; !ok && !contains(p.Exists, field) {
+		p.Exists = append(p.Exists, field)
+	}
+
+	return p.unset(field)
+}
+
+func contains(fields []string, field string) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *TodoPatch) forgetUnset(field string) {
+	for i, f := range p.Unset {
+		if f == field {
+			p.Unset = append(p.Unset[:i], p.Unset[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *TodoPatch) changes(field string) bool {
+	if _, ok := p.Set[field]; ok {
+		return true
+	}
+
+	return contains(p.Unset, field)
+}
+
+// fieldName matches the members of the documents with the todo fields regardless of their case,
+// so the todos returned by get-todo can be sent back as is.
+func fieldName(member string) string {
+	return strings.ToLower(member)
+}
+
+// parseMergePatch reads a RFC 7396 merge patch: every member sets its field, a null member removes it.
+func parseMergePatch(id string, body []byte) (*TodoPatch, error) {
+	var members map[string]json.RawMessage
+
+	err := json.Unmarshal(body, &members)
+	if err != nil || members == nil {
+		return nil, fmt.Errorf("%w: a merge patch is a json object", errMalformedPatch)
+	}
+
+	patch := newTodoPatch(id)
+
+	for member, raw := range members {
+		field := fieldName(member)
+
+		if string(raw) == "null" {
+			err = patch.unset(field)
+			if err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		value, err := todo.DecodeField(field, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		patch.set(field, value)
+	}
+
+	return patch, nil
+}
+
+// operation is an operation of a RFC 6902 json patch.
+type operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// invalidOperation fails the operation at index i of a json patch.
+func invalidOperation(i int, format string, args ...interface{}) error {
+	return messaging.Invalid(format, args...).WithDetail("operation", strconv.Itoa(i))
+}
+
+// parseJSONPatch reads a RFC 6902 json patch. Only add, replace, remove and test are supported,
+// the tests are checked against the todo as it was before the patch. The paths are the fields
+// of the todo, none of which has a ~ or a / in its name: the paths of their members or of the
+// elements of the tags aren't supported.
+func parseJSONPatch(id string, body []byte) (*TodoPatch, error) {
+	var operations []operation
+
+	err := json.Unmarshal(body, &operations)
+	if err != nil {
+		return nil, fmt.Errorf("%w: a json patch is an array of operations", errMalformedPatch)
+	}
+
+	patch := newTodoPatch(id)
+
+	for i, op := range operations {
+		if !strings.HasPrefix(op.Path, "/") || strings.Count(op.Path, "/") != 1 || strings.Contains(op.Path, "~") {
+			return nil, invalidOperation(i, "unsupported path %q, the path of a field of the todo is expected", op.Path)
+		}
+
+		field := fieldName(op.Path[1:])
+
+		if (op.Op == "add" || op.Op == "replace" || op.Op == "test") && op.Value == nil {
+			return nil, fmt.Errorf("%w: operation %d: missing value", errMalformedPatch, i)
+		}
+
+		switch op.Op {
+		case "add", "replace":
+			value, err := todo.DecodeField(field, op.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			patch.set(field, value)
+		case "remove":
+			err = patch.remove(field)
+			if err != nil {
+				return nil, err
+			}
+		case "test":
+			if patch.changes(field) {
+				return nil, invalidOperation(i, "can't test the field %q after changing it", field)
+			}
+
+			value, err := todo.DecodeField(field, op.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			patch.Test[field] = value
+		default:
+			return nil, invalidOperation(i, "unsupported operation %q", op.Op)
+		}
+	}
+
+	return patch, nil
+}
+
+// parseReplacement reads the todo replacing the stored one: the fields missing from it are removed,
+// and it isn't done unless it says so. Its checklist is kept, the items are changed on their own.
+func parseReplacement(id string, body []byte) (*TodoPatch, error) {
+	var members map[string]json.RawMessage
+
+	err := json.Unmarshal(body, &members)
+	if err != nil || members == nil {
+		return nil, fmt.Errorf("%w: a todo is a json object", errMalformedPatch)
+	}
+
+	patch := newTodoPatch(id)
+	patch.Upsert = true
+
+	for member, raw := range members {
+		field := fieldName(member)
+
+		if field == todo.FieldId {
+			var bodyId string
+			if json.Unmarshal(raw, &bodyId) != nil || bodyId != id {
+				return nil, messaging.Invalid("the id of the todo doesn't match the one of the url")
+			}
+
+			continue
+		}
+
+		if field == todo.FieldItems && string(raw) != "null" {
+			return nil, messaging.Invalid("the items of a todo aren't replaced along with it, change them with /todo/%s/items", id).
+				WithDetail("field", field)
+		}
+
+		if string(raw) == "null" || !todo.Editable(field) {
+			// the fields maintained by the DAOs, e.g. its version, are ignored
+			continue
+		}
+
+		value, err := todo.DecodeField(field, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		patch.set(field, value)
+	}
+
+	if !patch.changes(todo.FieldText) {
+		return nil, messaging.Invalid("the text of a todo can't be empty").WithDetail("field", todo.FieldText)
+	}
+
+	if !patch.changes(todo.FieldDone) {
+		patch.set(todo.FieldDone, false)
+	}
+
+	for _, field := range []string{todo.FieldListId, todo.FieldNotes, todo.FieldPriority, todo.FieldTags, todo.FieldDueAt, todo.FieldRecurrence} {
+		if !patch.changes(field) {
+			patch.unset(field)
+		}
+	}
+
+	return patch, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/todo"
+)
+
+// code returns the code of the Error err is, errMalformedPatch for a malformed document.
+func code(err error) string {
+	if errors.Is(err, errMalformedPatch) {
+		return "malformed"
+	}
+
+	var e *messaging.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	if err != nil {
+		return err.Error()
+	}
+
+	return ""
+}
+
+func TestParseMergePatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantSet   map[string]interface{}
+		wantUnset []string
+		wantErr   string
+	}{
+		{"set", `{"Text": "buy milk", "done": true}`, map[string]interface{}{todo.FieldText: "buy milk", todo.FieldDone: true}, nil, ""},
+		{"remove", `{"notes": null}`, map[string]interface{}{}, []string{todo.FieldNotes}, ""},
+		{"empty", `{}`, map[string]interface{}{}, nil, ""},
+		{"not an object", `[]`, nil, nil, "malformed"},
+		{"null", `null`, nil, nil, "malformed"},
+		{"remove the text", `{"text": null}`, nil, nil, messaging.CodeInvalid},
+		{"unknown field", `{"version": 3}`, nil, nil, messaging.CodeInvalid},
+		{"invalid priority", `{"priority": "whenever"}`, nil, nil, messaging.CodeInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := parseMergePatch("42", []byte(tt.body))
+			if code(err) != tt.wantErr {
+				t.Fatalf("parseMergePatch() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(patch.Set, tt.wantSet) || !reflect.DeepEqual(patch.Unset, tt.wantUnset) {
+				t.Errorf("parseMergePatch() = %v %v, want %v %v", patch.Set, patch.Unset, tt.wantSet, tt.wantUnset)
+			}
+		})
+	}
+}
+
+func TestParseJSONPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantSet    map[string]interface{}
+		wantUnset  []string
+		wantTest   map[string]interface{}
+		wantExists []string
+		wantErr    string
+	}{
+		{
+			name:     "test and replace",
+			body:     `[{"op": "test", "path": "/done", "value": false}, {"op": "replace", "path": "/Done", "value": true}]`,
+			wantSet:  map[string]interface{}{todo.FieldDone: true},
+			wantTest: map[string]interface{}{todo.FieldDone: false},
+		},
+		{
+			name:       "remove a stored field",
+			body:       `[{"op": "remove", "path": "/notes"}]`,
+			wantSet:    map[string]interface{}{},
+			wantUnset:  []string{todo.FieldNotes},
+			wantTest:   map[string]interface{}{},
+			wantExists: []string{todo.FieldNotes},
+		},
+		{
+			name:      "remove a field added by the patch",
+			body:      `[{"op": "add", "path": "/notes", "value": "n"}, {"op": "remove", "path": "/notes"}]`,
+			wantSet:   map[string]interface{}{},
+			wantUnset: []string{todo.FieldNotes},
+			wantTest:  map[string]interface{}{},
+		},
+		{
+			name:       "add a field removed by the patch",
+			body:       `[{"op": "remove", "path": "/notes"}, {"op": "add", "path": "/notes", "value": "n"}]`,
+			wantSet:    map[string]interface{}{todo.FieldNotes: "n"},
+			wantUnset:  []string{},
+			wantTest:   map[string]interface{}{},
+			wantExists: []string{todo.FieldNotes},
+		},
+		{name: "remove twice", body: `[{"op": "remove", "path": "/notes"}, {"op": "remove", "path": "/notes"}]`, wantErr: messaging.CodeInvalid},
+		{name: "remove the text", body: `[{"op": "remove", "path": "/text"}]`, wantErr: messaging.CodeInvalid},
+		{name: "test after a change", body: `[{"op": "replace", "path": "/done", "value": true}, {"op": "test", "path": "/done", "value": true}]`, wantErr: messaging.CodeInvalid},
+		{name: "nested path", body: `[{"op": "add", "path": "/tags/0", "value": "x"}]`, wantErr: messaging.CodeInvalid},
+		{name: "escaped path", body: `[{"op": "remove", "path": "/a~1b"}]`, wantErr: messaging.CodeInvalid},
+		{name: "relative path", body: `[{"op": "remove", "path": "notes"}]`, wantErr: messaging.CodeInvalid},
+		{name: "unsupported operation", body: `[{"op": "move", "from": "/notes", "path": "/text"}]`, wantErr: messaging.CodeInvalid},
+		{name: "missing value", body: `[{"op": "replace", "path": "/done"}]`, wantErr: "malformed"},
+		{name: "not an array", body: `{"op": "remove", "path": "/notes"}`, wantErr: "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := parseJSONPatch("42", []byte(tt.body))
+			if code(err) != tt.wantErr {
+				t.Fatalf("parseJSONPatch() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(patch.Set, tt.wantSet) || !reflect.DeepEqual(patch.Unset, tt.wantUnset) ||
+				!reflect.DeepEqual(patch.Test, tt.wantTest) || !reflect.DeepEqual(patch.Exists, tt.wantExists) {
+				t.Errorf("parseJSONPatch() = set %v unset %v test %v exists %v, want %v %v %v %v",
+					patch.Set, patch.Unset, patch.Test, patch.Exists, tt.wantSet, tt.wantUnset, tt.wantTest, tt.wantExists)
+			}
+		})
+	}
+}
+
+func TestParseReplacement(t *testing.T) {
+	removed := []string{todo.FieldListId, todo.FieldNotes, todo.FieldPriority, todo.FieldTags, todo.FieldDueAt, todo.FieldRecurrence}
+
+	tests := []struct {
+		name      string
+		body      string
+		wantSet   map[string]interface{}
+		wantUnset []string
+		wantErr   string
+	}{
+		{
+			name:      "text only",
+			body:      `{"text": "buy milk"}`,
+			wantSet:   map[string]interface{}{todo.FieldText: "buy milk", todo.FieldDone: false},
+			wantUnset: removed,
+		},
+		{
+			name:      "todo returned by get-todo",
+			body:      `{"Id": "42", "Text": "buy milk", "Done": true, "Notes": "2l", "Version": 3, "CreatedAt": "2024-01-01T00:00:00Z"}`,
+			wantSet:   map[string]interface{}{todo.FieldText: "buy milk", todo.FieldDone: true, todo.FieldNotes: "2l"},
+			wantUnset: []string{todo.FieldListId, todo.FieldPriority, todo.FieldTags, todo.FieldDueAt, todo.FieldRecurrence},
+		},
+		{
+			name:      "null items",
+			body:      `{"text": "buy milk", "items": null}`,
+			wantSet:   map[string]interface{}{todo.FieldText: "buy milk", todo.FieldDone: false},
+			wantUnset: removed,
+		},
+		{name: "items", body: `{"text": "buy milk", "items": [{"Text": "2l"}]}`, wantErr: messaging.CodeInvalid},
+		{name: "no items", body: `{"text": "buy milk", "items": []}`, wantErr: messaging.CodeInvalid},
+		{name: "no text", body: `{"done": true}`, wantErr: messaging.CodeInvalid},
+		{name: "another id", body: `{"id": "43", "text": "buy milk"}`, wantErr: messaging.CodeInvalid},
+		{name: "not an object", body: `"buy milk"`, wantErr: "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patch, err := parseReplacement("42", []byte(tt.body))
+			if code(err) != tt.wantErr {
+				t.Fatalf("parseReplacement() error = %v, want %q", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !patch.Upsert || !reflect.DeepEqual(patch.Set, tt.wantSet) || !reflect.DeepEqual(patch.Unset, tt.wantUnset) {
+				t.Errorf("parseReplacement() = upsert %v set %v unset %v, want an upsert %v %v", patch.Upsert, patch.Set, patch.Unset, tt.wantSet, tt.wantUnset)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"todo-go/pkg/auth"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/todo"
+
+	"github.com/gorilla/mux"
+)
+
+// ItemCreation is the command sent to post-dao to add an item to a todo, at the end of its
+// checklist unless Position is set.
+type ItemCreation struct {
+	TodoId   string
+	Text     string
+	Done     bool
+	Position *int `json:",omitempty"`
+}
+
+func postItem(w http.ResponseWriter, r *http.Request) {
+
+	reqBody, _ := ioutil.ReadAll(r.Body)
+
+	var dadosJson ItemCreation
+	err := json.Unmarshal(reqBody, &dadosJson)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Something went wrong while parsing the JSON from the request body.")
+		return
+	}
+
+	dadosJson.TodoId = mux.Vars(r)["id"]
+
+	item := todo.Item{Text: dadosJson.Text, Done: dadosJson.Done}
+	err = item.Validate()
+	if err == nil && dadosJson.Position != nil {
+		err = todo.ValidatePosition(*dadosJson.Position)
+	}
+
+	if err != nil {
+		problem.FromError(err).Write(w, r)
+		return
+	}
+
+	itemBytes, err := json.Marshal(dadosJson)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	corrId, err := rabbit.SendCommand(svcConfig.ItemOutboundQueueName, svcConfig.CommandQueueName, "post-item", itemBytes, auth.Headers(r.Context()))
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	acceptedResponse(w, r, corrId)
+}
+
+// items keeps only the fields of the items set by the client, the DAO gives them their ids.
+func items(posted []todo.Item) []todo.Item {
+	var kept []todo.Item
+	for _, item := range posted {
+		kept = append(kept, todo.Item{Text: item.Text, Done: item.Done})
+	}
+
+	return kept
+}
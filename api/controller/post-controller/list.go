@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"todo-go/pkg/problem"
+	"todo-go/pkg/todo"
+)
+
+func postList(w http.ResponseWriter, r *http.Request) {
+
+	headers, err := commandHeaders(r)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reqBody, _ := ioutil.ReadAll(r.Body)
+
+	var dadosJson todo.List
+	err = json.Unmarshal(reqBody, &dadosJson)
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, "Something went wrong while parsing the JSON from the request body.")
+		return
+	}
+
+	err = dadosJson.Validate()
+	if err != nil {
+		problem.FromError(err).Write(w, r)
+		return
+	}
+
+	// only the fields set by the client are sent, the DAO fills in the other ones
+	listBytes, err := json.Marshal(todo.List{
+		Name:        dadosJson.Name,
+		Description: dadosJson.Description,
+		Shares:      dadosJson.Shares,
+	})
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	corrId, err := rabbit.SendCommand(svcConfig.ListOutboundQueueName, svcConfig.CommandQueueName, "post-list", listBytes, headers)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	acceptedResponse(w, r, corrId)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"todo-go/pkg/auth"
+	"todo-go/pkg/etag"
+	"todo-go/pkg/problem"
+
+	"github.com/gorilla/mux"
+)
+
+// Restoration is the command sent to delete-dao to take a todo out of the trash.
+type Restoration struct {
+	Id      string
+	IfMatch []int64 `json:",omitempty"` // versions of the todo that can be restored
+}
+
+// restoreTodo takes the todo out of the trash, If-Match being checked against its version in the trash.
+func restoreTodo(w http.ResponseWriter, r *http.Request) {
+
+	var dadosJson Restoration
+	dadosJson.Id = mux.Vars(r)["id"]
+
+	ifMatch, err := etag.IfMatch(r.Header.Get("If-Match"))
+	if errors.Is(err, etag.ErrNoMatch) {
+		problem.Error(w, r, http.StatusPreconditionFailed, err.Error())
+		return
+	} else if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	dadosJson.IfMatch = ifMatch
+
+	todoBytes, err := json.Marshal(dadosJson)
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	corrId, err := rabbit.SendCommand(svcConfig.RestoreOutboundQueueName, svcConfig.CommandQueueName, "restore", todoBytes, auth.Headers(r.Context()))
+	if err != nil {
+		problem.Internal(w, r, err)
+		return
+	}
+
+	acceptedResponse(w, r, corrId)
+}
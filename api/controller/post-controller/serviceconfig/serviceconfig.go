@@ -0,0 +1,18 @@
+package serviceconfig
+
+import "todo-go/pkg/config"
+
+type ServiceConfig struct {
+	OutboundQueueName        string `required:"true"`
+	ListOutboundQueueName    string `required:"true"`
+	ItemOutboundQueueName    string `required:"true"`
+	RestoreOutboundQueueName string `required:"true"`
+	CommandQueueName         string `required:"true"`
+	Broker                   config.Broker
+	HTTP                     config.HTTP
+	JwtSecret                string   `required:"true"`
+	TenantDomain             string   // the subdomains of the domain name tenants, e.g. acme.todo.example.com
+	Tenants                  []string // the tenants that can be named besides the default one, * for any tenant
+	RateLimit                float64  `default:"10"` // the requests per second of a user or a client address
+	RateBurst                int      `default:"20"` // the requests they make at once
+}
@@ -0,0 +1,147 @@
+package hub
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = (pongWait * 9) / 10
+	maxMessageSize = 1024
+	sendBufferSize = 256
+)
+
+// ClientMessage is what a client sends to start or stop watching the commands it submitted.
+type ClientMessage struct {
+	Action        string // watch or unwatch
+	CorrelationId string
+}
+
+type Client struct {
+	hub      *Hub
+	conn     *websocket.Conn
+	send     chan []byte
+	userId   string // user authenticated by the handshake, only the events they can see are pushed
+	tenantId string // tenant of the user, the events of the other tenants are never pushed
+
+	mu       sync.RWMutex
+	watching map[string]bool
+}
+
+// Serve registers the connection of the user of the tenant in the hub and pumps messages until it is closed.
+func Serve(h *Hub, conn *websocket.Conn, tenantId string, userId string, correlationIds []string) {
+	c := &Client{
+		hub:      h,
+		conn:     conn,
+		send:     make(chan []byte, sendBufferSize),
+		userId:   userId,
+		tenantId: tenantId,
+		watching: make(map[string]bool),
+	}
+
+	for _, id := range correlationIds {
+		c.watch(id)
+	}
+
+	h.register(c)
+
+	go c.writePump()
+	c.readPump()
+}
+
+func (c *Client) watch(correlationId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.watching[correlationId] = true
+}
+
+func (c *Client) unwatch(correlationId string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.watching, correlationId)
+}
+
+func (c *Client) isWatching(correlationId string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.watching[correlationId]
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg ClientMessage
+		err := c.conn.ReadJSON(&msg)
+
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("Websocket closed unexpectedly:", err)
+			}
+
+			if _, ok := err.(*json.SyntaxError); ok {
+				continue
+			}
+
+			return
+		}
+
+		switch msg.Action {
+		case "watch":
+			c.watch(msg.CorrelationId)
+		case "unwatch":
+			c.unwatch(msg.CorrelationId)
+		default:
+			log.Printf("Unknown websocket action %q \n", msg.Action)
+		}
+	}
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
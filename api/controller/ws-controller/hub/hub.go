@@ -0,0 +1,124 @@
+package hub
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"todo-go/pkg/problem"
+)
+
+const (
+	EventCreated  = "created"
+	EventUpdated  = "updated"
+	EventDeleted  = "deleted"
+	EventRestored = "restored"
+	EventCommand  = "command"
+
+	EventListCreated = "list-created"
+	EventListUpdated = "list-updated"
+	EventListDeleted = "list-deleted"
+)
+
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Event is the message pushed to the websocket clients.
+type Event struct {
+	Type          string
+	CorrelationId string           `json:",omitempty"`
+	Operation     string           `json:",omitempty"`
+	Status        string           `json:",omitempty"`
+	Err           *problem.Problem `json:",omitempty"`
+	Todo          json.RawMessage  `json:",omitempty"`
+	List          json.RawMessage  `json:",omitempty"`
+}
+
+// IsListEvent tells whether the event is about a list rather than a todo.
+func IsListEvent(eventType string) bool {
+	switch eventType {
+	case EventListCreated, EventListUpdated, EventListDeleted:
+		return true
+	}
+
+	return false
+}
+
+// Hub keeps track of the connected clients and fans the todo events out to them.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[*Client]bool
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: make(map[*Client]bool)}
+}
+
+func (h *Hub) register(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.clients[c] = true
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.clients[c]; ok {
+		delete(h.clients, c)
+		close(c.send)
+	}
+}
+
+// Audience is the users of a tenant allowed to see an event.
+type Audience struct {
+	TenantId string
+	Users    map[string]bool
+}
+
+func (a Audience) includes(c *Client) bool {
+	return c.tenantId == a.TenantId && a.Users[c.userId]
+}
+
+// Broadcast pushes the event to every client of the users in its audience.
+func (h *Hub) Broadcast(event Event, audience Audience) {
+	h.dispatch(event, audience.includes)
+}
+
+// Notify pushes the event only to the clients of the audience watching its correlation id.
+func (h *Hub) Notify(event Event, audience Audience) {
+	h.dispatch(event, func(c *Client) bool { return audience.includes(c) && c.isWatching(event.CorrelationId) })
+}
+
+func (h *Hub) dispatch(event Event, filter func(c *Client) bool) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Println("Failed to parse the event to JSON:", err)
+		return
+	}
+
+	var slow []*Client
+
+	h.mu.RLock()
+	for c := range h.clients {
+		if !filter(c) {
+			continue
+		}
+
+		select {
+		case c.send <- data:
+		default:
+			slow = append(slow, c)
+		}
+	}
+	h.mu.RUnlock()
+
+	// clients that can't keep up are dropped instead of blocking everyone else
+	for _, c := range slow {
+		log.Println("Dropping slow websocket client", c.conn.RemoteAddr())
+		h.unregister(c)
+	}
+}
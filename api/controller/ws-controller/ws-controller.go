@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"todo-go/pkg/auth"
+	"todo-go/pkg/config"
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/ratelimit"
+	"todo-go/pkg/tenant"
+	"ws-todo/hub"
+	"ws-todo/serviceconfig"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/streadway/amqp"
+)
+
+var todoHub = hub.NewHub()
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+func failOnError(err error, msg string) {
+	if err != nil {
+		log.Fatalf("%s: %s", msg, err)
+	}
+}
+
+func main() {
+	fmt.Printf("Starting the amazing API to watch TODOs\n")
+
+	// the port is the one the proxy sends the requests to unless it is set
+	c := serviceconfig.ServiceConfig{HTTP: config.HTTP{Port: 10005}}
+	err := config.Load("ws", &c)
+	failOnError(err, "There was a problem loading the service configs.")
+
+	keys, err := auth.NewKeys(c.JwtSecret)
+	failOnError(err, "There was a problem loading the secret of the tokens.")
+
+	tenants, err := tenant.NewResolver(c.TenantDomain, c.Tenants)
+	failOnError(err, "There was a problem loading the tenants.")
+
+	keys.Tenants(tenants)
+
+	limiter, err := ratelimit.New(c.RateLimit, c.RateBurst)
+	failOnError(err, "There was a problem loading the rate limit.")
+
+	events := messaging.NewSubscriber(c.Broker.URL(), c.EventsExchangeName, c.ConsumerName, handleEvent).
+		TLS(c.Broker.TLS.Config())
+
+	go events.ListenAndServe()
+
+	setupApiRouter(c.HTTP, keys, limiter)
+}
+
+func setupApiRouter(server config.HTTP, keys *auth.Keys, limiter *ratelimit.Limiter) {
+	router := mux.NewRouter().StrictSlash(true)
+
+	router.Path("/todo/ws").Methods(http.MethodGet).HandlerFunc(websocketHandler)
+	router.Path("/todo/ws/health").Methods(http.MethodGet).HandlerFunc(healthCheckHandler)
+
+	router.Use(setupLoggingMiddleware)
+	router.Use(limiter.AddressMiddleware("/todo/ws/health"))
+	router.Use(keys.Middleware("/todo/ws/health"))
+	router.Use(limiter.Middleware("/todo/ws/health"))
+
+	log.Fatal(server.Server(router).ListenAndServe())
+}
+
+func setupLoggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the query of a handshake carries the access token of the client, it isn't logged
+		log.Println(r.URL.Path)
+		log.Println(r.RemoteAddr)
+		// Call the next handler, which can be another middleware in the chain, or the final handler.
+		next.ServeHTTP(w, r)
+	})
+}
+
+func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != http.MethodGet {
+		problem.Error(w, r, http.StatusMethodNotAllowed, "Method is not supported.")
+		return
+	}
+
+	fmt.Fprintf(w, "We're good to go.")
+}
+
+func websocketHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Println("Failed to upgrade the websocket connection:", err)
+		return
+	}
+
+	// clients may start watching the commands they already submitted, e.g. /todo/ws?correlationId=abc
+	hub.Serve(todoHub, conn, tenant.Id(r.Context()), auth.UserId(r.Context()), r.URL.Query()["correlationId"])
+}
+
+// handleEvent pushes a todo event published by a DAO to the websocket clients of its audience.
+func handleEvent(d amqp.Delivery) {
+	var data messaging.Result
+	err := json.Unmarshal(d.Body, &data)
+
+	if err != nil {
+		log.Println("Failed to parse the event published by the DAO:", err)
+		return
+	}
+
+	// an event without audience is seen by nobody, and one without tenant is of the default one
+	audience := hub.Audience{TenantId: tenant.Default, Users: make(map[string]bool)}
+	if users, ok := d.Headers[messaging.AudienceHeader].(string); ok && users != "" {
+		for _, user := range strings.Split(users, ",") {
+			audience.Users[user] = true
+		}
+	}
+
+	if id, ok := d.Headers[messaging.TenantHeader].(string); ok && id != "" {
+		audience.TenantId = id
+	}
+
+	pushEvent(d.Type, d.CorrelationId, data, audience)
+}
+
+func pushEvent(eventType string, correlationId string, data messaging.Result, audience hub.Audience) {
+	command := hub.Event{
+		Type:          hub.EventCommand,
+		CorrelationId: correlationId,
+		Operation:     eventType,
+		Status:        hub.StatusSucceeded,
+	}
+
+	if data.Err != nil {
+		command.Status = hub.StatusFailed
+		command.Err = problem.FromError(data.Err)
+		command.Err.Instance = "/todo/commands/" + correlationId
+	} else {
+		result := json.RawMessage(data.Result)
+		if !json.Valid(result) {
+			result = nil
+		}
+
+		if hub.IsListEvent(eventType) {
+			command.List = result
+		} else {
+			command.Todo = result
+		}
+
+		todoHub.Broadcast(hub.Event{Type: eventType, Todo: command.Todo, List: command.List}, audience)
+	}
+
+	if correlationId != "" {
+		todoHub.Notify(command, audience)
+	}
+}
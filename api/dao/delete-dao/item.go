@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ItemDeletion is the command sent by delete-todo for an item of a todo.
+type ItemDeletion struct {
+	TodoId  string
+	ItemId  string
+	IfMatch []int64 `json:",omitempty"` // versions of the todo the item can be removed from
+}
+
+func handleItemRequest(req *messaging.Request) (interface{}, error) {
+	var deletion ItemDeletion
+
+	err := req.Decode(&deletion)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := store.UserOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := tenants.Of(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.AuditOf(req).Run(ctx, bson.M{todo.FieldId: deletion.TodoId}, func(ctx context.Context) (interface{}, error) {
+		err := store.AuthorizeTodo(ctx, userId, deletion.TodoId)
+		if err != nil {
+			return nil, err
+		}
+
+		return deleteItem(ctx, deletion)
+	})
+}
+
+// deleteItem removes the item from its todo and returns the todo, its completion derived from
+// the remaining items. A todo left without items keeps its completion.
+func deleteItem(ctx context.Context, deletion ItemDeletion) (*todo.Todo, error) {
+	now := todo.Now()
+
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			todo.FieldItems: bson.M{"$filter": bson.M{
+				"input": "$" + todo.FieldItems,
+				"cond":  bson.M{"$ne": bson.A{"$$this.id", deletion.ItemId}},
+			}},
+			todo.FieldVersion:   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + todo.FieldVersion, 0}}, 1}},
+			todo.FieldUpdatedAt: now,
+		}},
+	}
+	update = append(update, store.Completion(now)...)
+
+	filter := bson.M{todo.FieldId: deletion.TodoId, todo.FieldDeletedAt: nil, todo.FieldItems + ".id": deletion.ItemId}
+	if len(deletion.IfMatch) > 0 {
+		filter[todo.FieldVersion] = store.VersionIn(deletion.IfMatch)
+	}
+
+	var updated todo.Todo
+	err := store.Todos(ctx).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, itemNotDeleted(ctx, deletion)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// itemNotDeleted tells apart a todo or an item that doesn't exist from a todo at another version than the If-Match one.
+func itemNotDeleted(ctx context.Context, deletion ItemDeletion) error {
+	var current struct {
+		Version int64
+	}
+
+	err := store.Todos(ctx).FindOne(ctx, bson.M{todo.FieldId: deletion.TodoId, todo.FieldDeletedAt: nil}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return messaging.NotFound("todo %s not found", deletion.TodoId).WithDetail("id", deletion.TodoId)
+	} else if err != nil {
+		return err
+	}
+
+	if len(deletion.IfMatch) > 0 && !store.ContainsVersion(deletion.IfMatch, current.Version) {
+		return messaging.PreconditionFailed("todo %s was changed, it is now at version %d", deletion.TodoId, current.Version).
+			WithDetail("id", deletion.TodoId).
+			WithDetail("version", strconv.FormatInt(current.Version, 10))
+	}
+
+	return messaging.NotFound("item %s of todo %s not found", deletion.ItemId, deletion.TodoId).
+		WithDetail("id", deletion.TodoId).
+		WithDetail("itemId", deletion.ItemId)
+}
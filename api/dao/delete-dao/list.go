@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ListDeletion is the command sent by delete-todo for a list.
+type ListDeletion struct {
+	Id      string
+	IfMatch []int64 `json:",omitempty"` // versions of the list that can be deleted
+}
+
+// DeletedList is a deleted list along with the number of its todos moved to the trash with it.
+type DeletedList struct {
+	todo.List
+	DeletedTodos int64
+}
+
+func handleListRequest(req *messaging.Request) (interface{}, error) {
+	var listJson ListDeletion
+
+	err := req.Decode(&listJson)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := store.UserOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := tenants.Of(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// the todos moved to the trash along with the list are recorded in their history
+	return store.AuditOf(req).Run(ctx, bson.M{todo.FieldListId: listJson.Id}, func(ctx context.Context) (interface{}, error) {
+		err := store.AuthorizeList(ctx, userId, listJson.Id, store.Owner)
+		if err != nil {
+			return nil, err
+		}
+
+		return deleteList(ctx, listJson)
+	})
+}
+
+// deleteList deletes the list and moves its todos to the trash, in the transaction recording their history.
+func deleteList(ctx context.Context, deletion ListDeletion) (*DeletedList, error) {
+	filter := bson.M{todo.FieldId: deletion.Id}
+	if len(deletion.IfMatch) > 0 {
+		filter[todo.FieldVersion] = store.VersionIn(deletion.IfMatch)
+	}
+
+	err := store.Lists(ctx).FindOne(ctx, filter).Err()
+	if err == mongo.ErrNoDocuments {
+		return nil, listNotDeleted(ctx, deletion)
+	} else if err != nil {
+		return nil, err
+	}
+
+	// the todos of the list go to the trash, the ones already there stay as they are
+	now := todo.Now()
+	res, err := store.Todos(ctx).UpdateMany(ctx, bson.M{todo.FieldListId: deletion.Id, todo.FieldDeletedAt: nil}, bson.M{
+		"$inc": bson.M{todo.FieldVersion: 1},
+		"$set": bson.M{todo.FieldDeletedAt: now, todo.FieldUpdatedAt: now},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var deleted DeletedList
+	err = store.Lists(ctx).FindOneAndDelete(ctx, filter).Decode(&deleted.List)
+
+	if err == mongo.ErrNoDocuments {
+		// the list changed while its todos were moved to the trash
+		return nil, listNotDeleted(ctx, deletion)
+	} else if err != nil {
+		return nil, err
+	}
+
+	deleted.DeletedTodos = res.ModifiedCount
+	return &deleted, nil
+}
+
+// deletedListAudience returns the users the deleted list was shared with, along with its owner.
+func deletedListAudience(req *messaging.Request, result interface{}) []string {
+	if deleted, ok := result.(*DeletedList); ok {
+		return store.ListAudience(&deleted.List)
+	}
+
+	return tenants.Audience(req, result)
+}
+
+// listNotDeleted tells apart a list that doesn't exist from a list at another version than the If-Match one.
+func listNotDeleted(ctx context.Context, deletion ListDeletion) error {
+	var current struct {
+		Version int64
+	}
+
+	err := store.Lists(ctx).FindOne(ctx, bson.M{todo.FieldId: deletion.Id}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return messaging.NotFound("list %s not found", deletion.Id).WithDetail("listId", deletion.Id)
+	} else if err != nil {
+		return err
+	}
+
+	return messaging.PreconditionFailed("list %s was changed, it is now at version %d", deletion.Id, current.Version).
+		WithDetail("listId", deletion.Id).
+		WithDetail("version", strconv.FormatInt(current.Version, 10))
+}
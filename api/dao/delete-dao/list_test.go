@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+
+	"todo-go/pkg/store"
+	"todo-go/pkg/tenant"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestDeleteListTrashesTodos checks the todos of a deleted list go to the trash, where they can be restored from.
+func TestDeleteListTrashesTodos(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("delete list", func(mt *mtest.T) {
+		tenants = store.NewTenants(mt.Client, func(db *store.DB) error { return nil })
+
+		list := bson.D{{Key: todo.FieldId, Value: "groceries"}, {Key: "ownerid", Value: "alice"}}
+		mt.AddMockResponses(
+			// the history index of the tenant is created first
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, "todoDB.lists", mtest.FirstBatch, list),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}),
+			mtest.CreateSuccessResponse(bson.E{Key: "value", Value: list}),
+		)
+
+		ctx, err := tenants.With(tenant.Default)
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		deleted, err := deleteList(ctx, ListDeletion{Id: "groceries"})
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		if deleted.Id != "groceries" || deleted.DeletedTodos != 2 {
+			mt.Errorf("deleteList() = %+v, want list groceries with 2 todos moved to the trash", deleted)
+		}
+
+		mt.GetStartedEvent() // createIndexes
+		mt.GetStartedEvent() // find
+
+		update := mt.GetStartedEvent()
+		if update.CommandName != "update" {
+			mt.Fatalf("command = %s, want the todos updated rather than deleted", update.CommandName)
+		}
+
+		set := update.Command.Lookup("updates", "0", "u", "$set").Document()
+		if _, err := set.LookupErr(todo.FieldDeletedAt); err != nil {
+			mt.Errorf("update = %s, want %s set", set, todo.FieldDeletedAt)
+		}
+
+		if multi, _ := update.Command.Lookup("updates", "0", "multi").BooleanOK(); !multi {
+			mt.Error("only one todo of the list is moved to the trash")
+		}
+	})
+}
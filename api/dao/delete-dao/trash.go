@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"time"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/tenant"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TodoRestoration is the command sent by post-todo to take a todo out of the trash.
+type TodoRestoration struct {
+	Id      string
+	IfMatch []int64 `json:",omitempty"` // versions of the todo that can be restored
+}
+
+func handleRestoreRequest(req *messaging.Request) (interface{}, error) {
+	var restoration TodoRestoration
+
+	err := req.Decode(&restoration)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := store.UserOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := tenants.Of(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.AuditOf(req).Run(ctx, bson.M{todo.FieldId: restoration.Id}, func(ctx context.Context) (interface{}, error) {
+		err := store.AuthorizeTodo(ctx, userId, restoration.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		return restoreTodo(ctx, restoration)
+	})
+}
+
+// restoreTodo takes the todo out of the trash, as it was before it was deleted. A todo whose list was
+// deleted since is restored out of any list.
+func restoreTodo(ctx context.Context, restoration TodoRestoration) (*todo.Todo, error) {
+	filter := bson.M{todo.FieldId: restoration.Id, todo.FieldDeletedAt: bson.M{"$ne": nil}}
+	if len(restoration.IfMatch) > 0 {
+		filter[todo.FieldVersion] = store.VersionIn(restoration.IfMatch)
+	}
+
+	unset := bson.M{todo.FieldDeletedAt: ""}
+
+	gone, err := listGone(ctx, restoration.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if gone {
+		unset[todo.FieldListId] = ""
+	}
+
+	update := bson.M{
+		"$inc":   bson.M{todo.FieldVersion: 1},
+		"$set":   bson.M{todo.FieldUpdatedAt: todo.Now()},
+		"$unset": unset,
+	}
+
+	var restored todo.Todo
+	err = store.Todos(ctx).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&restored)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, notRestored(ctx, restoration)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &restored, nil
+}
+
+// listGone tells if the todo is in a list that doesn't exist anymore, e.g. it was deleted with its list.
+func listGone(ctx context.Context, todoId string) (bool, error) {
+	var current todo.Todo
+
+	err := store.Todos(ctx).FindOne(ctx, bson.M{todo.FieldId: todoId}).Decode(&current)
+	if err == mongo.ErrNoDocuments || (err == nil && current.ListId == "") {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	count, err := store.Lists(ctx).CountDocuments(ctx, bson.M{todo.FieldId: current.ListId})
+	return count == 0, err
+}
+
+// notRestored tells apart a todo that doesn't exist or isn't in the trash from a todo at another version than the If-Match one.
+func notRestored(ctx context.Context, restoration TodoRestoration) error {
+	var current todo.Todo
+
+	err := store.Todos(ctx).FindOne(ctx, bson.M{todo.FieldId: restoration.Id}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return messaging.NotFound("todo %s not found", restoration.Id).WithDetail("id", restoration.Id)
+	} else if err != nil {
+		return err
+	}
+
+	if current.DeletedAt == nil {
+		return messaging.Conflict("todo %s isn't in the trash", restoration.Id).WithDetail("id", restoration.Id)
+	}
+
+	return messaging.PreconditionFailed("todo %s was changed, it is now at version %d", restoration.Id, current.Version).
+		WithDetail("id", restoration.Id).
+		WithDetail("version", strconv.FormatInt(current.Version, 10))
+}
+
+// purgeBatch is the number of todos purged in a transaction.
+const purgeBatch = 100
+
+// purgeTrash deletes for good, every interval, the todos of every tenant deleted more than retention ago.
+func purgeTrash(retention time.Duration, interval time.Duration) {
+	for {
+		before := todo.Now().Add(-retention)
+
+		ids, err := tenantIds()
+		if err != nil {
+			log.Println("Failed to list the tenants:", err)
+		}
+
+		for _, id := range ids {
+			purged, err := purgeTenant(id, before)
+			if err != nil {
+				log.Printf("Failed to purge the trash of tenant %s: %s", id, err)
+			} else if purged > 0 {
+				log.Printf("Purged %d todos of tenant %s deleted before %s", purged, id, before.Format(time.RFC3339))
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// tenantIds returns the tenants having a database, the ones not used since the DAO started included.
+func tenantIds() ([]string, error) {
+	names, err := client.ListDatabaseNames(ctx, bson.M{"name": bson.M{"$regex": "^" + store.Database}})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, name := range names {
+		if id, ok := tenant.FromDatabase(store.Database, name); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func purgeTenant(id string, before time.Time) (int64, error) {
+	ctx, err := tenants.With(id)
+	if err != nil {
+		return 0, err
+	}
+
+	return purge(ctx, before)
+}
+
+// purge deletes the todos deleted before the time by batches, recording their deletion in their history.
+func purge(ctx context.Context, before time.Time) (int64, error) {
+	expired := bson.M{todo.FieldDeletedAt: bson.M{"$lt": before}}
+	var purged int64
+
+	for {
+		var batch []todo.Todo
+
+		cur, err := store.Todos(ctx).Find(ctx, expired, options.Find().SetProjection(bson.M{todo.FieldId: 1}).SetLimit(purgeBatch))
+		if err == nil {
+			err = cur.All(ctx, &batch)
+		}
+
+		if err != nil || len(batch) == 0 {
+			return purged, err
+		}
+
+		ids := bson.A{}
+		for _, t := range batch {
+			ids = append(ids, t.Id)
+		}
+
+		// a todo restored since the batch was read isn't expired anymore: the transaction
+		// neither deletes it nor records its purge
+		filter := bson.M{"$and": bson.A{bson.M{todo.FieldId: bson.M{"$in": ids}}, expired}}
+
+		res, err := store.Audit{Operation: "purge"}.Run(ctx, filter, func(ctx context.Context) (interface{}, error) {
+			return store.Todos(ctx).DeleteMany(ctx, filter)
+		})
+
+		if err != nil {
+			return purged, err
+		}
+
+		purged += res.(*mongo.DeleteResult).DeletedCount
+	}
+}
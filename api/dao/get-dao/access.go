@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// readableLists matches the lists the user sees: theirs and the ones shared with them.
+func readableLists(userId string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{todo.FieldOwnerId: userId},
+		bson.M{todo.FieldShares + "." + todo.FieldUserId: userId},
+	}}
+}
+
+// readableTodos matches the todos the user sees: theirs and the ones of the lists they see.
+func readableTodos(ctx context.Context, userId string) (bson.M, error) {
+	cur, err := store.Lists(ctx).Find(ctx, readableLists(userId), options.Find().SetProjection(bson.M{todo.FieldId: 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var readable []todo.List
+
+	err = cur.All(ctx, &readable)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := bson.A{}
+	for _, l := range readable {
+		ids = append(ids, l.Id)
+	}
+
+	return bson.M{"$or": bson.A{
+		bson.M{todo.FieldOwnerId: userId},
+		bson.M{todo.FieldListId: bson.M{"$in": ids}},
+	}}, nil
+}
+
+// sees tells whether the user sees the todo: they own it, or they see its list.
+func sees(ctx context.Context, userId string, t *todo.Todo) (bool, error) {
+	if t.OwnerId == userId {
+		return true, nil
+	}
+
+	if t.ListId == "" {
+		return false, nil
+	}
+
+	err := store.Lists(ctx).FindOne(ctx, bson.M{"$and": bson.A{bson.M{todo.FieldId: t.ListId}, readableLists(userId)}}).Err()
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+
+	return err == nil, err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// getHistory returns the last changes of the todo, the last one first. The history of a todo
+// is kept after it is deleted, it is only seen by the users who see the todo, until it is purged.
+func getHistory(ctx context.Context, userId string, query TodoQuery) ([]store.HistoryEntry, error) {
+	if query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+
+	if query.Limit < 0 || query.Limit > MaxLimit {
+		return nil, messaging.Invalid("the limit must be between 1 and %d", MaxLimit)
+	}
+
+	_, err := findTodo(ctx, userId, query.Id, bson.M{todo.FieldId: query.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}}).
+		SetLimit(int64(query.Limit))
+
+	cur, err := store.History(ctx).Find(ctx, bson.M{"todoid": query.Id}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// a todo stored before its changes were recorded has no history yet
+	entries := []store.HistoryEntry{}
+	err = cur.All(ctx, &entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxLists is the number of lists returned by a listing, there is no next page.
+const MaxLists = 500
+
+// ListQuery is the request sent by get-todo for the lists: a single list when Id is set, all of them otherwise.
+type ListQuery struct {
+	Id string
+}
+
+func handleListRequest(req *messaging.Request) (interface{}, error) {
+	var query ListQuery
+
+	err := req.Decode(&query)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := store.UserOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := tenants.Of(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Id != "" {
+		return getList(ctx, userId, query.Id)
+	}
+
+	return listLists(ctx, userId)
+}
+
+// getList returns the list, if the user sees it.
+func getList(ctx context.Context, userId string, id string) (*todo.List, error) {
+	var found todo.List
+
+	err := store.Lists(ctx).FindOne(ctx, bson.M{"$and": bson.A{bson.M{todo.FieldId: id}, readableLists(userId)}}).Decode(&found)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, messaging.NotFound("list %s not found", id).WithDetail("listId", id)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &found, nil
+}
+
+// listLists returns the lists the user sees sorted by name.
+func listLists(ctx context.Context, userId string) ([]todo.List, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: todo.FieldName, Value: 1}, {Key: todo.FieldId, Value: 1}}).
+		SetLimit(MaxLists)
+
+	cur, err := store.Lists(ctx).Find(ctx, readableLists(userId), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	found := []todo.List{}
+
+	err = cur.All(ctx, &found)
+	if err != nil {
+		return nil, err
+	}
+
+	return found, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxWindow is the longest window the occurrences are listed in.
+const MaxWindow = 366 * 24 * time.Hour
+
+// Occurrence is a pending todo due in the window of the query, or a later occurrence of a recurring
+// one. A projected occurrence doesn't exist yet, it is created once the todo before it is done.
+type Occurrence struct {
+	TodoId    string // the todo, or the pending todo a projected occurrence follows
+	SeriesId  string `json:",omitempty"`
+	Text      string
+	DueAt     time.Time
+	Projected bool
+}
+
+// listOccurrences returns the first occurrences of the todos the user sees due between From, included,
+// and To, excluded, sorted by due date. The recurring todos due before the window may repeat in it.
+func listOccurrences(ctx context.Context, userId string, query TodoQuery) ([]Occurrence, error) {
+	if query.From == nil || query.To == nil {
+		return nil, messaging.Invalid("the window of the occurrences needs a start and an end")
+	}
+
+	from, to := query.From.UTC(), query.To.UTC()
+
+	if !from.Before(to) || to.Sub(from) > MaxWindow {
+		return nil, messaging.Invalid("the window of the occurrences ends after it starts, at most %d days later", int(MaxWindow.Hours()/24))
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+
+	if query.Limit < 0 || query.Limit > MaxLimit {
+		return nil, messaging.Invalid("the limit must be between 1 and %d", MaxLimit)
+	}
+
+	filter, err := todoFilter(ctx, userId, query)
+	if err != nil {
+		return nil, err
+	}
+
+	filter[todo.FieldDone] = false
+	filter[todo.FieldDueAt] = bson.M{"$lt": to}
+	filter["$or"] = bson.A{
+		bson.M{todo.FieldDueAt: bson.M{"$gte": from}},
+		bson.M{todo.FieldRecurrence: bson.M{"$exists": true}},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: todo.FieldDueAt, Value: 1}, {Key: todo.FieldId, Value: 1}})
+
+	cur, err := store.Todos(ctx).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	defer cur.Close(ctx)
+
+	occurrences := []Occurrence{}
+
+	for cur.Next(ctx) {
+		var found todo.Todo
+		err := cur.Decode(&found)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the occurrences of the next todos are due after this one
+		if len(occurrences) == query.Limit && occurrences[query.Limit-1].DueAt.Before(*found.DueAt) {
+			break
+		}
+
+		occurrences = append(occurrences, occurrencesOf(found, from, to, query.Limit)...)
+
+		sort.SliceStable(occurrences, func(i, j int) bool {
+			return occurrences[i].DueAt.Before(occurrences[j].DueAt)
+		})
+
+		if len(occurrences) > query.Limit {
+			occurrences = occurrences[:query.Limit]
+		}
+	}
+
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	return occurrences, nil
+}
+
+// occurrencesOf returns the todo if it is due in the window, followed by the next occurrences in it if it is recurring.
+func occurrencesOf(found todo.Todo, from time.Time, to time.Time, max int) []Occurrence {
+	seriesId := found.SeriesId
+	if seriesId == "" && found.Recurrence != "" {
+		seriesId = found.Id
+	}
+
+	occurrences := []Occurrence{}
+
+	if !found.DueAt.Before(from) {
+		occurrences = append(occurrences, Occurrence{TodoId: found.Id, SeriesId: seriesId, Text: found.Text, DueAt: *found.DueAt})
+	}
+
+	if found.Recurrence == "" {
+		return occurrences
+	}
+
+	for _, dueAt := range todo.Occurrences(found.Recurrence, *found.DueAt, from, to, max) {
+		occurrences = append(occurrences, Occurrence{TodoId: found.Id, SeriesId: seriesId, Text: found.Text, DueAt: dueAt, Projected: true})
+	}
+
+	return occurrences
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Page sizes of the listings.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+const defaultSort = "-createdAt"
+
+// defaultTrashSort lists the todos deleted last first.
+const defaultTrashSort = "-deletedAt"
+
+// TodoQuery is the request sent by get-todo: a single todo when Id is set, the occurrences due in a
+// window when From and To are, a page of the todos otherwise.
+type TodoQuery struct {
+	Id       string
+	ListId   string // list the todos belong to
+	Done     *bool
+	Q        string // text the todos contain, case insensitive
+	Tag      string // tag the todos have
+	Priority string
+	Sort     string // field to sort by, descending when prefixed by -
+	Limit    int
+	Cursor   string // NextCursor of the previous page
+	Trashed  bool   // lists the todos in the trash instead of the other ones
+	History  bool   // returns the last changes of the todo with Id instead of the todo
+
+	// window of the occurrences of the pending todos, listed instead of the todos when set
+	From *time.Time
+	To   *time.Time
+}
+
+// TodoPage is a page of the todos, NextCursor is empty on the last one.
+type TodoPage struct {
+	Todos      []todo.Todo
+	NextCursor string `json:",omitempty"`
+}
+
+// sortFields maps the fields the todos can be sorted by to their name in the collection,
+// along with the decoding of their values in a cursor.
+var sortFields = map[string]struct {
+	name   string
+	decode func(json.RawMessage) (interface{}, error)
+}{
+	"createdAt": {todo.FieldCreatedAt, decodeTime},
+	"updatedAt": {todo.FieldUpdatedAt, decodeTime},
+	"dueAt":     {todo.FieldDueAt, decodeTime},
+	"text":      {todo.FieldText, decodeString},
+	"done":      {todo.FieldDone, decodeBool},
+	"deletedAt": {todo.FieldDeletedAt, decodeTime},
+}
+
+func decodeTime(raw json.RawMessage) (interface{}, error) {
+	var t *time.Time
+	err := json.Unmarshal(raw, &t)
+	if t == nil {
+		return nil, err
+	}
+
+	return *t, err
+}
+
+func decodeString(raw json.RawMessage) (interface{}, error) {
+	var s *string
+	err := json.Unmarshal(raw, &s)
+	if s == nil {
+		return nil, err
+	}
+
+	return *s, err
+}
+
+func decodeBool(raw json.RawMessage) (interface{}, error) {
+	var b *bool
+	err := json.Unmarshal(raw, &b)
+	if b == nil {
+		return nil, err
+	}
+
+	return *b, err
+}
+
+// cursor is the position of the last todo of a page, the next page starts right after it.
+type cursor struct {
+	Sort  string
+	Value json.RawMessage // value of the sort field, null for the todos without it
+	Id    string
+}
+
+func encodeCursor(c cursor) (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, messaging.Invalid("invalid cursor")
+	}
+
+	var c cursor
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return nil, messaging.Invalid("invalid cursor")
+	}
+
+	return &c, nil
+}
+
+// listTodos returns the page of the todos the user sees matching the query.
+func listTodos(ctx context.Context, userId string, query TodoQuery) (*TodoPage, error) {
+	if query.Sort == "" && query.Trashed {
+		query.Sort = defaultTrashSort
+	} else if query.Sort == "" {
+		query.Sort = defaultSort
+	}
+
+	if query.Limit == 0 {
+		query.Limit = DefaultLimit
+	}
+
+	if query.Limit < 0 || query.Limit > MaxLimit {
+		return nil, messaging.Invalid("the limit must be between 1 and %d", MaxLimit)
+	}
+
+	field := strings.TrimPrefix(query.Sort, "-")
+	sortField, ok := sortFields[field]
+	if !ok {
+		return nil, messaging.Invalid("the todos can't be sorted by %q", field).WithDetail("sort", query.Sort)
+	}
+
+	direction := 1
+	if strings.HasPrefix(query.Sort, "-") {
+		direction = -1
+	}
+
+	filter, err := todoFilter(ctx, userId, query)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Done != nil {
+		filter[todo.FieldDone] = *query.Done
+	}
+
+	if query.Cursor != "" {
+		after, err := afterCursor(query, sortField.name, sortField.decode, direction)
+		if err != nil {
+			return nil, err
+		}
+
+		filter = bson.M{"$and": bson.A{filter, after}}
+	}
+
+	// one more todo tells whether there is a next page
+	opts := options.Find().
+		SetSort(bson.D{{Key: sortField.name, Value: direction}, {Key: todo.FieldId, Value: direction}}).
+		SetLimit(int64(query.Limit + 1))
+
+	cur, err := store.Todos(ctx).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	defer cur.Close(ctx)
+
+	page := TodoPage{Todos: []todo.Todo{}}
+
+	for cur.Next(ctx) {
+		var found todo.Todo
+		err := cur.Decode(&found)
+
+		if err != nil {
+			return nil, err
+		}
+
+		page.Todos = append(page.Todos, found)
+	}
+
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Todos) > query.Limit {
+		page.Todos = page.Todos[:query.Limit]
+
+		page.NextCursor, err = nextCursor(query.Sort, field, page.Todos[query.Limit-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &page, nil
+}
+
+// todoFilter matches the todos the user sees of the list, text, tag and priority of the query,
+// either in the trash or out of it. The user sees every todo of a list they see.
+func todoFilter(ctx context.Context, userId string, query TodoQuery) (bson.M, error) {
+	filter := bson.M{todo.FieldDeletedAt: nil}
+	if query.Trashed {
+		filter[todo.FieldDeletedAt] = bson.M{"$ne": nil}
+	}
+
+	if query.ListId != "" {
+		_, err := getList(ctx, userId, query.ListId)
+		if err != nil {
+			return nil, err
+		}
+
+		filter[todo.FieldListId] = query.ListId
+	} else {
+		readable, err := readableTodos(ctx, userId)
+		if err != nil {
+			return nil, err
+		}
+
+		// the occurrences add their own $or
+		filter["$and"] = bson.A{readable}
+	}
+
+	if query.Q != "" {
+		filter[todo.FieldText] = bson.M{"$regex": regexp.QuoteMeta(query.Q), "$options": "i"}
+	}
+
+	if query.Tag != "" {
+		filter[todo.FieldTags] = query.Tag
+	}
+
+	if query.Priority != "" {
+		filter[todo.FieldPriority] = query.Priority
+	}
+
+	return filter, nil
+}
+
+func nextCursor(sort string, field string, last todo.Todo) (string, error) {
+	var value interface{}
+
+	switch field {
+	case "createdAt":
+		if last.CreatedAt != nil {
+			value = last.CreatedAt
+		}
+	case "updatedAt":
+		if last.UpdatedAt != nil {
+			value = last.UpdatedAt
+		}
+	case "dueAt":
+		if last.DueAt != nil {
+			value = last.DueAt
+		}
+	case "deletedAt":
+		if last.DeletedAt != nil {
+			value = last.DeletedAt
+		}
+	case "text":
+		value = last.Text
+	case "done":
+		value = last.Done
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeCursor(cursor{Sort: sort, Value: raw, Id: last.Id})
+}
+
+// afterCursor filters the todos coming after the cursor in the sort order. The todos without the
+// sort field come first in ascending order and last in descending order.
+func afterCursor(query TodoQuery, name string, decode func(json.RawMessage) (interface{}, error), direction int) (bson.M, error) {
+	c, err := decodeCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Sort != query.Sort {
+		return nil, messaging.Invalid("the cursor was made for another sort order").WithDetail("sort", c.Sort)
+	}
+
+	value, err := decode(c.Value)
+	if err != nil {
+		return nil, messaging.Invalid("invalid cursor")
+	}
+
+	next := "$gt"
+	if direction < 0 {
+		next = "$lt"
+	}
+
+	if value == nil {
+		after := bson.M{name: nil, "id": bson.M{next: c.Id}}
+		if direction < 0 {
+			return after, nil
+		}
+
+		return bson.M{"$or": bson.A{after, bson.M{name: bson.M{"$ne": nil}}}}, nil
+	}
+
+	or := bson.A{
+		bson.M{name: bson.M{next: value}},
+		bson.M{name: value, "id": bson.M{next: c.Id}},
+	}
+
+	if direction < 0 {
+		or = append(or, bson.M{name: nil})
+	}
+
+	return bson.M{"$or": or}, nil
+}
+
+// createIndexes creates the indexes the listings of a tenant are sorted with, a filter on done uses its own.
+// The tags and the owners are indexed on their own, a listing of a tag is usually short, and so are the lists.
+func createIndexes(db *store.DB) error {
+	_, err := db.Lists.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: todo.FieldOwnerId, Value: 1}}},
+		{Keys: bson.D{{Key: todo.FieldShares + "." + todo.FieldUserId, Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	models := []mongo.IndexModel{
+		{Keys: bson.D{{Key: todo.FieldTags, Value: 1}}},
+		{Keys: bson.D{{Key: todo.FieldOwnerId, Value: 1}}},
+		{Keys: bson.D{{Key: todo.FieldListId, Value: 1}, {Key: todo.FieldCreatedAt, Value: 1}, {Key: todo.FieldId, Value: 1}}},
+	}
+
+	for _, field := range sortFields {
+		models = append(models, mongo.IndexModel{Keys: bson.D{{Key: field.name, Value: 1}, {Key: "id", Value: 1}}})
+
+		if field.name != "done" {
+			models = append(models, mongo.IndexModel{Keys: bson.D{{Key: "done", Value: 1}, {Key: field.name, Value: 1}, {Key: "id", Value: 1}}})
+		}
+	}
+
+	_, err = db.Todos.Indexes().CreateMany(ctx, models)
+	return err
+}
@@ -3,6 +3,9 @@ module todo-go-patch-dao
 go 1.16
 
 require (
-	github.com/streadway/amqp v1.0.0
+	github.com/google/uuid v1.3.0
 	go.mongodb.org/mongo-driver v1.3.1
+	todo-go/pkg v0.0.0
 )
+
+replace todo-go/pkg => ../../pkg
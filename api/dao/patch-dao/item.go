@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ItemPatch is the command sent by patch-todo for an item of a todo: the fields to set, and the
+// position it is moved to if any, applied only if the version of the todo is one of IfMatch.
+type ItemPatch struct {
+	TodoId   string
+	ItemId   string
+	Set      map[string]json.RawMessage
+	Position *int
+	IfMatch  []int64
+}
+
+func handleItemRequest(req *messaging.Request) (interface{}, error) {
+	var patch ItemPatch
+
+	err := req.Decode(&patch)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := store.UserOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := tenants.Of(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.AuditOf(req).Run(ctx, audited(patch.TodoId, patch.Set), func(ctx context.Context) (interface{}, error) {
+		err := store.AuthorizeTodo(ctx, userId, patch.TodoId)
+		if err != nil {
+			return nil, err
+		}
+
+		updated, err := updateItem(ctx, patch)
+		if err != nil || !completes(patch.Set) {
+			return updated, err
+		}
+
+		// completing the last item completes the todo
+		return repeat(ctx, updated)
+	})
+}
+
+// updateItem changes the item and derives the completion of its todo from the items.
+func updateItem(ctx context.Context, patch ItemPatch) (*todo.Todo, error) {
+	if patch.TodoId == "" || patch.ItemId == "" {
+		return nil, messaging.Invalid("missing todo or item id")
+	}
+
+	set := bson.M{}
+	for field, raw := range patch.Set {
+		value, err := todo.DecodeItemField(field, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		set[field] = value
+	}
+
+	if patch.Position != nil {
+		err := todo.ValidatePosition(*patch.Position)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	now := todo.Now()
+	items := "$" + todo.FieldItems
+
+	// the values are literals, a text starting with $ isn't a field
+	changed := bson.M{"$map": bson.M{
+		"input": items,
+		"in": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$$this.id", patch.ItemId}},
+			bson.M{"$mergeObjects": bson.A{"$$this", bson.M{"$literal": set}}},
+			"$$this",
+		}},
+	}}
+
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			todo.FieldItems:     changed,
+			todo.FieldVersion:   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + todo.FieldVersion, 0}}, 1}},
+			todo.FieldUpdatedAt: now,
+		}},
+	}
+
+	if patch.Position != nil {
+		update = append(update, bson.M{"$set": bson.M{todo.FieldItems: moved(patch.ItemId, *patch.Position)}})
+	}
+
+	update = append(update, store.Completion(now)...)
+
+	filter := bson.M{todo.FieldId: patch.TodoId, todo.FieldDeletedAt: nil, todo.FieldItems + ".id": patch.ItemId}
+	if len(patch.IfMatch) > 0 {
+		filter[todo.FieldVersion] = store.VersionIn(patch.IfMatch)
+	}
+
+	var updated todo.Todo
+	err := store.Todos(ctx).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, itemNotMatched(ctx, patch.TodoId, patch.ItemId, patch.IfMatch)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// moved is the checklist with the item moved to the position, or to the end when the position is past it.
+func moved(itemId string, position int) bson.M {
+	others := bson.M{"$filter": bson.M{
+		"input": "$" + todo.FieldItems,
+		"cond":  bson.M{"$ne": bson.A{"$$this.id", itemId}},
+	}}
+
+	item := bson.M{"$filter": bson.M{
+		"input": "$" + todo.FieldItems,
+		"cond":  bson.M{"$eq": bson.A{"$$this.id", itemId}},
+	}}
+
+	if position == 0 {
+		return bson.M{"$concatArrays": bson.A{item, others}}
+	}
+
+	return bson.M{"$concatArrays": bson.A{
+		bson.M{"$slice": bson.A{others, position}},
+		item,
+		bson.M{"$slice": bson.A{others, position, bson.M{"$add": bson.A{bson.M{"$size": others}, 1}}}},
+	}}
+}
+
+// itemNotMatched tells apart a todo or an item that doesn't exist from a todo at another version than the If-Match one.
+func itemNotMatched(ctx context.Context, todoId string, itemId string, ifMatch []int64) error {
+	var current todo.Todo
+
+	err := store.Todos(ctx).FindOne(ctx, bson.M{todo.FieldId: todoId, todo.FieldDeletedAt: nil}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return messaging.NotFound("todo %s not found", todoId).WithDetail("id", todoId)
+	} else if err != nil {
+		return err
+	}
+
+	if len(ifMatch) > 0 && !store.ContainsVersion(ifMatch, current.Version) {
+		return messaging.PreconditionFailed("todo %s was changed, it is now at version %d", todoId, current.Version).
+			WithDetail("id", todoId).
+			WithDetail("version", strconv.FormatInt(current.Version, 10))
+	}
+
+	return messaging.NotFound("item %s of todo %s not found", itemId, todoId).
+		WithDetail("id", todoId).
+		WithDetail("itemId", itemId)
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ListPatch is the command sent by patch-todo for a list: the fields to set and to remove,
+// applied only if its version is one of IfMatch.
+type ListPatch struct {
+	Id      string
+	Set     map[string]json.RawMessage
+	Unset   []string
+	IfMatch []int64
+}
+
+func handleListRequest(req *messaging.Request) (interface{}, error) {
+	var patch ListPatch
+
+	err := req.Decode(&patch)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := store.UserOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := tenants.Of(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateList(ctx, userId, patch)
+}
+
+// updateList applies only the fields of the patch to the list, which has to exist and be owned by the user.
+func updateList(ctx context.Context, userId string, patch ListPatch) (*todo.List, error) {
+	if patch.Id == "" {
+		return nil, messaging.Invalid("missing list id")
+	}
+
+	err := store.AuthorizeList(ctx, userId, patch.Id, store.Owner)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{todo.FieldUpdatedAt: todo.Now()}
+	for field, raw := range patch.Set {
+		value, err := todo.DecodeListField(field, raw)
+		if err != nil {
+			return nil, err
+		}
+
+		set[field] = value
+	}
+
+	if shares, ok := set[todo.FieldShares].([]todo.Share); ok {
+		err = store.CheckShares(userId, shares)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{todo.FieldVersion: 1},
+		"$set": set,
+	}
+
+	if len(patch.Unset) > 0 {
+		unset := bson.M{}
+		for _, field := range patch.Unset {
+			if !todo.ListRemovable(field) {
+				return nil, messaging.Invalid("the field %q can't be removed", field).WithDetail("field", field)
+			}
+
+			unset[field] = ""
+		}
+
+		update["$unset"] = unset
+	}
+
+	filter := bson.M{todo.FieldId: patch.Id, todo.FieldOwnerId: userId}
+	if len(patch.IfMatch) > 0 {
+		filter[todo.FieldVersion] = store.VersionIn(patch.IfMatch)
+	}
+
+	var updated todo.List
+	err = store.Lists(ctx).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+
+	if err == mongo.ErrNoDocuments {
+		return nil, listNotMatched(ctx, patch)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// listNotMatched tells apart a list that doesn't exist from a list at another version than the If-Match one.
+func listNotMatched(ctx context.Context, patch ListPatch) error {
+	var current todo.List
+
+	err := store.Lists(ctx).FindOne(ctx, bson.M{todo.FieldId: patch.Id}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return messaging.NotFound("list %s not found", patch.Id).WithDetail("listId", patch.Id)
+	} else if err != nil {
+		return err
+	}
+
+	return messaging.PreconditionFailed("list %s was changed, it is now at version %d", patch.Id, current.Version).
+		WithDetail("listId", patch.Id).
+		WithDetail("version", strconv.FormatInt(current.Version, 10))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/store"
+	"todo-go/pkg/tenant"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// TestUpsertOverQuota checks a PUT, or an If-None-Match: * creation, can't insert a todo past the quota of its user.
+func TestUpsertOverQuota(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	set := map[string]json.RawMessage{"text": json.RawMessage(`"buy milk"`)}
+	count := mtest.CreateCursorResponse(0, "todoDB.todos", mtest.FirstBatch, bson.D{{Key: "n", Value: int64(2)}})
+	missing := mtest.CreateCursorResponse(0, "todoDB.todos", mtest.FirstBatch)
+
+	tests := []struct {
+		name      string
+		patch     TodoPatch
+		responses []bson.D
+	}{
+		// the todo is looked up first, an upsert of an existing todo only updates it
+		{"put", TodoPatch{Id: "42", Set: set, Upsert: true}, []bson.D{missing, count}},
+		{"create only", TodoPatch{Id: "42", Set: set, Upsert: true, CreateOnly: true}, []bson.D{count}},
+	}
+
+	for _, tt := range tests {
+		mt.Run(tt.name, func(mt *mtest.T) {
+			maxTodos = 2
+			tenants = store.NewTenants(mt.Client, func(db *store.DB) error { return nil })
+
+			// the history index of the tenant is created first
+			mt.AddMockResponses(mtest.CreateSuccessResponse())
+			mt.AddMockResponses(tt.responses...)
+
+			ctx, err := tenants.With(tenant.Default)
+			if err != nil {
+				mt.Fatal(err)
+			}
+
+			_, err = updateTodo(ctx, "alice", tt.patch)
+
+			var e *messaging.Error
+			if !errors.As(err, &e) || e.Code != messaging.CodeQuotaExceeded || problem.Status(e.Code) != http.StatusConflict {
+				mt.Fatalf("updateTodo() error = %v, want a %s error answered with a 409", err, messaging.CodeQuotaExceeded)
+			}
+
+			if e.Details["limit"] != "2" {
+				mt.Errorf("limit = %q, want 2", e.Details["limit"])
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// occurrenceSpace is the namespace of the ids of the occurrences, the next occurrence of a todo
+// always gets the same id so that completing it again, or retrying the command, doesn't repeat it twice.
+var occurrenceSpace = uuid.MustParse("2669c301-7679-4003-b115-ac01e7c8115a")
+
+// Repeated is a recurring todo just completed, along with its next occurrence.
+type Repeated struct {
+	todo.Todo
+	Next *todo.Todo
+}
+
+// repeatedAudience returns the users allowed to see a todo changed, and repeated, by a patch.
+// The next occurrence is in the same list as the completed todo.
+func repeatedAudience(req *messaging.Request, result interface{}) []string {
+	if r, ok := result.(*Repeated); ok {
+		return tenants.Audience(req, &r.Todo)
+	}
+
+	return tenants.Audience(req, result)
+}
+
+// completes tells whether the patch marks the todo, or the item, done.
+func completes(set map[string]json.RawMessage) bool {
+	var done bool
+	err := json.Unmarshal(set[todo.FieldDone], &done)
+	return err == nil && done
+}
+
+// repeat creates the next occurrence of the completed todo if it is recurring and its rule didn't end,
+// due at the next date of the rule after its due date, or after its completion when it has none.
+func repeat(ctx context.Context, completed *todo.Todo) (interface{}, error) {
+	if !completed.Done || completed.Recurrence == "" {
+		return completed, nil
+	}
+
+	at := completed.CompletedAt
+	if completed.DueAt != nil {
+		at = completed.DueAt
+	}
+
+	if at == nil {
+		return completed, nil
+	}
+
+	dueAt, rule, ok := todo.NextOccurrence(completed.Recurrence, *at)
+	if !ok {
+		return completed, nil
+	}
+
+	seriesId := completed.SeriesId
+	if seriesId == "" {
+		seriesId = completed.Id
+	}
+
+	next := todo.Todo{
+		OwnerId:    completed.OwnerId,
+		ListId:     completed.ListId,
+		Text:       completed.Text,
+		Notes:      completed.Notes,
+		Priority:   completed.Priority,
+		Tags:       completed.Tags,
+		DueAt:      &dueAt,
+		Recurrence: rule,
+		SeriesId:   seriesId,
+	}
+
+	for _, item := range completed.Items {
+		next.Items = append(next.Items, todo.Item{Id: item.Id, Text: item.Text})
+	}
+
+	next.Created(occurrenceId(completed.Id), todo.Now())
+
+	// the occurrence may have been created already, and changed since
+	var existing todo.Todo
+	err := store.Todos(ctx).FindOne(ctx, bson.M{todo.FieldId: next.Id}).Decode(&existing)
+	if err == nil {
+		return &Repeated{*completed, &existing}, nil
+	} else if err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+
+	// the next occurrence is a new todo of the owner of the series
+	err = store.CheckQuota(ctx, next.OwnerId, maxTodos)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = store.Todos(ctx).InsertOne(ctx, next)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repeated{*completed, &next}, nil
+}
+
+// occurrenceId returns the id of the occurrence following the todo.
+func occurrenceId(todoId string) string {
+	return uuid.NewSHA1(occurrenceSpace, []byte(todoId)).String()
+}
+
+// audited matches the todo a patch changes, along with its next occurrence when the patch completes it.
+func audited(todoId string, set map[string]json.RawMessage) bson.M {
+	ids := bson.A{todoId}
+	if completes(set) {
+		ids = append(ids, occurrenceId(todoId))
+	}
+
+	return bson.M{todo.FieldId: bson.M{"$in": ids}}
+}
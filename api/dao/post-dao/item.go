@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"strconv"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ItemCreation is the command sent by post-todo to add an item to a todo, at the end of its
+// checklist unless Position is set.
+type ItemCreation struct {
+	TodoId   string
+	Text     string
+	Done     bool
+	Position *int
+}
+
+func handleItemRequest(req *messaging.Request) (interface{}, error) {
+	var creation ItemCreation
+
+	err := req.Decode(&creation)
+	if err != nil {
+		return nil, err
+	}
+
+	item := todo.Item{Text: creation.Text, Done: creation.Done}
+
+	err = item.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := store.UserOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := tenants.Of(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if creation.Position != nil {
+		err = todo.ValidatePosition(*creation.Position)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// a retried or replayed command adds the same item, it is only added once
+	item.Id = req.CorrelationId
+
+	return store.AuditOf(req).Run(ctx, bson.M{todo.FieldId: creation.TodoId}, func(ctx context.Context) (interface{}, error) {
+		err := store.AuthorizeTodo(ctx, userId, creation.TodoId)
+		if err != nil {
+			return nil, err
+		}
+
+		return addItem(ctx, creation.TodoId, item, creation.Position)
+	})
+}
+
+// addItem inserts the item in the checklist of the todo and derives its completion from the items.
+func addItem(ctx context.Context, todoId string, item todo.Item, position *int) (*todo.Todo, error) {
+	items := bson.M{"$ifNull": bson.A{"$" + todo.FieldItems, bson.A{}}}
+	added := bson.M{"$literal": bson.A{item}}
+
+	var inserted interface{}
+	switch {
+	case position == nil:
+		inserted = bson.M{"$concatArrays": bson.A{items, added}}
+	case *position == 0:
+		inserted = bson.M{"$concatArrays": bson.A{added, items}}
+	default:
+		inserted = bson.M{"$concatArrays": bson.A{
+			bson.M{"$slice": bson.A{items, *position}},
+			added,
+			bson.M{"$slice": bson.A{items, *position, bson.M{"$add": bson.A{bson.M{"$size": items}, 1}}}},
+		}}
+	}
+
+	now := todo.Now()
+
+	update := bson.A{
+		bson.M{"$set": bson.M{
+			todo.FieldItems:     inserted,
+			todo.FieldVersion:   bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$" + todo.FieldVersion, 0}}, 1}},
+			todo.FieldUpdatedAt: now,
+		}},
+	}
+	update = append(update, store.Completion(now)...)
+
+	// the todo doesn't have the item yet and has room for it
+	filter := bson.M{
+		todo.FieldId:            todoId,
+		todo.FieldDeletedAt:     nil,
+		todo.FieldItems + ".id": bson.M{"$ne": item.Id},
+		todo.FieldItems + "." + strconv.Itoa(todo.MaxItems-1): bson.M{"$exists": false},
+	}
+
+	var updated todo.Todo
+	err := store.Todos(ctx).FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+
+	if err == mongo.ErrNoDocuments {
+		return itemNotAdded(ctx, todoId, item)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// itemNotAdded tells apart a todo that doesn't exist, a todo that already has the item and a full checklist.
+func itemNotAdded(ctx context.Context, todoId string, item todo.Item) (*todo.Todo, error) {
+	var current todo.Todo
+
+	err := store.Todos(ctx).FindOne(ctx, bson.M{todo.FieldId: todoId, todo.FieldDeletedAt: nil}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return nil, messaging.NotFound("todo %s not found", todoId).WithDetail("id", todoId)
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, i := range current.Items {
+		if i.Id == item.Id {
+			return &current, nil
+		}
+	}
+
+	return nil, messaging.Invalid("todo %s can't have more than %d items", todoId, todo.MaxItems).WithDetail("id", todoId)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/mongodb"
+	"todo-go/pkg/store"
+	"todo-go/pkg/todo"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func handleListRequest(req *messaging.Request) (interface{}, error) {
+	var listJson todo.List
+
+	err := req.Decode(&listJson)
+	if err != nil {
+		return nil, err
+	}
+
+	err = listJson.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	userId, err := store.UserOf(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, err := tenants.Of(req)
+	if err != nil {
+		return nil, err
+	}
+
+	err = store.CheckShares(userId, listJson.Shares)
+	if err != nil {
+		return nil, err
+	}
+
+	listJson.Created(uuid.New().String(), todo.Now())
+	listJson.OwnerId = userId
+	listJson.IdempotencyKey = req.IdempotencyKey()
+
+	return insertList(ctx, listJson)
+}
+
+// insertList inserts the list, unless its owner already created a list with the same idempotency key,
+// in which case that one is returned.
+func insertList(ctx context.Context, newList todo.List) (*todo.List, error) {
+	_, err := store.Lists(ctx).InsertOne(ctx, newList)
+
+	if newList.IdempotencyKey != "" && mongodb.IsDuplicateKey(err) {
+		log.Printf("List with idempotency key %q already created \n", newList.IdempotencyKey)
+
+		var existing todo.List
+		err = store.Lists(ctx).FindOne(ctx, bson.M{todo.FieldOwnerId: newList.OwnerId, todo.FieldIdempotencyKey: newList.IdempotencyKey}).Decode(&existing)
+		if err != nil {
+			return nil, err
+		}
+
+		return &existing, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &newList, nil
+}
@@ -0,0 +1,206 @@
+// Package auth issues and verifies the JWT bearer tokens of the API users.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/tenant"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Issuer is the issuer of the tokens, the tokens of another issuer are rejected.
+const Issuer = "todo-go"
+
+// MinSecretLength is the length of the shortest secret the tokens are signed with.
+const MinSecretLength = 32
+
+// AccessTokenParam is the query parameter carrying the token of the websocket clients,
+// browsers can't set the Authorization header of a websocket handshake.
+const AccessTokenParam = "access_token"
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the claims of an access token, its subject is the id of the user of the tenant.
+type Claims struct {
+	Name   string `json:"name,omitempty"`
+	Tenant string `json:"tenant,omitempty"` // tenant of the user, none for the default tenant
+	jwt.RegisteredClaims
+}
+
+// Keys signs and verifies the tokens with the secret shared by the auth service and the controllers.
+type Keys struct {
+	secret  []byte
+	tenants *tenant.Resolver
+}
+
+func NewKeys(secret string) (*Keys, error) {
+	if len(secret) < MinSecretLength {
+		return nil, fmt.Errorf("the secret signing the tokens must be at least %d characters long", MinSecretLength)
+	}
+
+	// the tenant of a token was allowed by the auth service already
+	return &Keys{secret: []byte(secret), tenants: &tenant.Resolver{Any: true}}, nil
+}
+
+// Tenants makes the middleware resolve the tenant the requests name with the resolver,
+// by default it is only named by the tenant.Header of the requests.
+func (k *Keys) Tenants(resolver *tenant.Resolver) *Keys {
+	k.tenants = resolver
+	return k
+}
+
+// NewToken returns an access token of the user of the tenant expiring after ttl.
+func (k *Keys) NewToken(userId string, name string, tenantId string, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	if tenantId == tenant.Default {
+		tenantId = ""
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Name:   name,
+		Tenant: tenantId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    Issuer,
+			Subject:   userId,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+
+	return token.SignedString(k.secret)
+}
+
+// Parse returns the claims of a valid access token, signed by the keys and not expired.
+func (k *Keys) Parse(token string) (*Claims, error) {
+	var claims Claims
+
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %s", t.Header["alg"])
+		}
+
+		return k.secret, nil
+	})
+
+	if err != nil || !parsed.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	if claims.Issuer != Issuer || claims.Subject == "" || claims.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+
+	if _, err := tenant.Of(claims.Tenant); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &claims, nil
+}
+
+// Middleware rejects with a 401 the requests without a valid bearer token, but the ones to the public
+// paths, e.g. the health checks. The user of the token is available to the handlers with UserId, and
+// their tenant with tenant.Id. A request naming another tenant than the one of the token is forbidden.
+func (k *Keys) Middleware(public ...string) func(http.Handler) http.Handler {
+	open := make(map[string]bool, len(public))
+	for _, path := range public {
+		open[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if open[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(r)
+			if !ok {
+				unauthorized(w, r, "The request needs a bearer token.")
+				return
+			}
+
+			claims, err := k.Parse(token)
+			if err != nil {
+				unauthorized(w, r, "The bearer token is invalid or expired.")
+				return
+			}
+
+			requested, err := k.tenants.Resolve(r)
+			if err != nil {
+				problem.Error(w, r, http.StatusBadRequest, err.Error())
+				return
+			}
+
+			tenantId, _ := tenant.Of(claims.Tenant)
+			if requested != "" && requested != tenantId {
+				problem.Error(w, r, http.StatusForbidden, "The bearer token isn't valid for tenant "+requested+".")
+				return
+			}
+
+			ctx := tenant.WithId(WithUserId(r.Context(), claims.Subject), tenantId)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// bearerToken reads the token of the Authorization header, or of the query of a websocket handshake.
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if header != "" {
+		scheme, token, ok := cut(header, " ")
+		return token, ok && strings.EqualFold(scheme, "Bearer") && token != ""
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		token := r.URL.Query().Get(AccessTokenParam)
+		return token, token != ""
+	}
+
+	return "", false
+}
+
+func cut(s string, sep string) (string, string, bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i+len(sep):]), true
+	}
+
+	return s, "", false
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="todo-go"`)
+	problem.Error(w, r, http.StatusUnauthorized, detail)
+}
+
+type userIdKey struct{}
+
+// WithUserId returns a copy of the context carrying the id of the authenticated user.
+func WithUserId(ctx context.Context, userId string) context.Context {
+	return context.WithValue(ctx, userIdKey{}, userId)
+}
+
+// UserId returns the id of the authenticated user the request is made by, if any.
+func UserId(ctx context.Context) string {
+	id, _ := ctx.Value(userIdKey{}).(string)
+	return id
+}
+
+// Headers returns the headers sending the user and their tenant along with a message to the DAOs,
+// see messaging.UserIdHeader and messaging.TenantHeader.
+func Headers(ctx context.Context) map[string]string {
+	userId := UserId(ctx)
+	if userId == "" {
+		return nil
+	}
+
+	return map[string]string{messaging.UserIdHeader: userId, messaging.TenantHeader: tenant.Id(ctx)}
+}
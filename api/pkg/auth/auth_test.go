@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo-go/pkg/tenant"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+func newKeys(t *testing.T) *Keys {
+	k, err := NewKeys(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return k
+}
+
+// sign returns a token of the claims, signed with the secret of the keys unless another key is given.
+func sign(t *testing.T, method jwt.SigningMethod, claims Claims, key interface{}) string {
+	if key == nil {
+		key = []byte(secret)
+	}
+
+	token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestNewKeys(t *testing.T) {
+	if _, err := NewKeys("too short"); err == nil {
+		t.Error("NewKeys() accepted a secret shorter than MinSecretLength")
+	}
+}
+
+func TestParse(t *testing.T) {
+	k := newKeys(t)
+
+	valid := func() Claims {
+		return Claims{
+			Tenant: "acme",
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    Issuer,
+				Subject:   "alice",
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		}
+	}
+
+	with := func(change func(c *Claims)) Claims {
+		c := valid()
+		change(&c)
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"valid", sign(t, jwt.SigningMethodHS256, valid(), nil), true},
+		{"other algorithm", sign(t, jwt.SigningMethodHS512, valid(), nil), false},
+		{"none algorithm", sign(t, jwt.SigningMethodNone, valid(), jwt.UnsafeAllowNoneSignatureType), false},
+		{"bad signature", sign(t, jwt.SigningMethodHS256, valid(), []byte("another secret of thirty-two chars")), false},
+		{"other issuer", sign(t, jwt.SigningMethodHS256, with(func(c *Claims) { c.Issuer = "someone" }), nil), false},
+		{"no subject", sign(t, jwt.SigningMethodHS256, with(func(c *Claims) { c.Subject = "" }), nil), false},
+		{"no expiry", sign(t, jwt.SigningMethodHS256, with(func(c *Claims) { c.ExpiresAt = nil }), nil), false},
+		{"expired", sign(t, jwt.SigningMethodHS256, with(func(c *Claims) {
+			c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+		}), nil), false},
+		{"invalid tenant", sign(t, jwt.SigningMethodHS256, with(func(c *Claims) { c.Tenant = "Acme!" }), nil), false},
+		{"not a token", "not.a.token", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := k.Parse(tt.token)
+			if !tt.ok {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("Parse() error = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+
+			if err != nil || claims.Subject != "alice" || claims.Tenant != "acme" {
+				t.Errorf("Parse() = %+v, %v, want the claims of alice", claims, err)
+			}
+		})
+	}
+}
+
+func TestNewToken(t *testing.T) {
+	k := newKeys(t)
+
+	token, err := k.NewToken("alice", "Alice", tenant.Default, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := k.Parse(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the default tenant isn't written in the tokens
+	if claims.Subject != "alice" || claims.Name != "Alice" || claims.Tenant != "" {
+		t.Errorf("Parse(NewToken()) = %+v", claims)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	k := newKeys(t)
+
+	resolver, err := tenant.NewResolver("", []string{"acme", "globex"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Tenants(resolver)
+
+	acme, err := k.NewToken("alice", "Alice", "acme", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var userId, tenantId string
+	handler := k.Middleware("/health")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId, tenantId = UserId(r.Context()), tenant.Id(r.Context())
+	}))
+
+	tests := []struct {
+		name          string
+		path          string
+		authorization string
+		tenant        string
+		want          int
+		wantUser      string
+	}{
+		{"public path", "/health", "", "", http.StatusOK, ""},
+		{"no token", "/todo", "", "", http.StatusUnauthorized, ""},
+		{"other scheme", "/todo", "Basic " + acme, "", http.StatusUnauthorized, ""},
+		{"no token after the scheme", "/todo", "Bearer ", "", http.StatusUnauthorized, ""},
+		{"malformed token", "/todo", "Bearer abc", "", http.StatusUnauthorized, ""},
+		{"valid", "/todo", "Bearer " + acme, "", http.StatusOK, "alice"},
+		{"scheme in lower case", "/todo", "bearer " + acme, "acme", http.StatusOK, "alice"},
+		{"other tenant", "/todo", "Bearer " + acme, "globex", http.StatusForbidden, ""},
+		{"unknown tenant", "/todo", "Bearer " + acme, "initech", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userId, tenantId = "", ""
+
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.tenant != "" {
+				r.Header.Set(tenant.Header, tt.tenant)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+
+			if tt.want == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("no WWW-Authenticate header")
+			}
+
+			if userId != tt.wantUser {
+				t.Errorf("UserId() = %q, want %q", userId, tt.wantUser)
+			}
+
+			if tt.wantUser != "" && tenantId != "acme" {
+				t.Errorf("tenant.Id() = %q, want acme", tenantId)
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		upgrade       string
+		query         string
+		want          string
+		ok            bool
+	}{
+		{"header", "Bearer abc", "", "", "abc", true},
+		{"header over query", "Bearer abc", "websocket", "?access_token=def", "abc", true},
+		{"query of a websocket handshake", "", "websocket", "?access_token=def", "def", true},
+		{"query of another request", "", "", "?access_token=def", "", false},
+		{"query of another upgrade", "", "h2c", "?access_token=def", "", false},
+		{"websocket handshake without token", "", "websocket", "", "", false},
+		{"nothing", "", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/todo/ws"+tt.query, nil)
+			if tt.authorization != "" {
+				r.Header.Set("Authorization", tt.authorization)
+			}
+			if tt.upgrade != "" {
+				r.Header.Set("Upgrade", tt.upgrade)
+			}
+
+			got, ok := bearerToken(r)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("bearerToken() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Package config loads the settings of the services from the environment, an optional YAML file and
+// Docker secrets, and checks them before the service starts.
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/kelseyhightower/envconfig"
+	"gopkg.in/yaml.v2"
+)
+
+// FileVar names the YAML file of the settings of a service, e.g. /etc/todo/post-dao.yml.
+const FileVar = "CONFIG_FILE"
+
+// SecretSuffix ends the variables naming the file holding the value of a setting, e.g. a Docker secret:
+// POSTDAO_DATABASE_PASSWORD_FILE=/run/secrets/mongo-password sets POSTDAO_DATABASE_PASSWORD.
+const SecretSuffix = "_FILE"
+
+// Validator is implemented by the settings checking themselves, Load calls it on the spec and every struct in it.
+type Validator interface {
+	Validate() error
+}
+
+// Load reads the settings of the service into spec, as envconfig.Process does with the prefix. A setting is
+// read from its variable, or from the file named by its variable with SecretSuffix, or else from the YAML file
+// named by FileVar, whose keys are the ones of the variables without the prefix, split at the structs:
+//
+//	broker:
+//	  url: amqps://broker.example.com:5671/
+//	  tls:
+//	    enabled: true
+//
+// sets PREFIX_BROKER_URL and PREFIX_BROKER_TLS_ENABLED. The settings are validated once loaded.
+func Load(prefix string, spec interface{}) error {
+	prefix = strings.ToUpper(prefix)
+
+	err := readSecrets(prefix)
+	if err != nil {
+		return err
+	}
+
+	err = readFile(prefix, os.Getenv(FileVar))
+	if err != nil {
+		return err
+	}
+
+	err = envconfig.Process(prefix, spec)
+	if err != nil {
+		return err
+	}
+
+	return validate(reflect.ValueOf(spec), prefix)
+}
+
+// readSecrets sets the variables of the service named by a variable ending with SecretSuffix to the content of its file.
+func readSecrets(prefix string) error {
+	for _, env := range os.Environ() {
+		kv := strings.SplitN(env, "=", 2)
+		name, path := kv[0], kv[len(kv)-1]
+		if !strings.HasPrefix(name, prefix+"_") || !strings.HasSuffix(name, SecretSuffix) {
+			continue
+		}
+
+		name = strings.TrimSuffix(name, SecretSuffix)
+		if _, ok := os.LookupEnv(name); ok {
+			return fmt.Errorf("both %s and %s%s are set, set either", name, name, SecretSuffix)
+		}
+
+		value, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s%s: %w", name, SecretSuffix, err)
+		}
+
+		// the files written by hand usually end with a new line
+		os.Setenv(name, strings.TrimRight(string(value), "\r\n"))
+	}
+
+	return nil
+}
+
+// readFile sets the variables of the service the environment doesn't set to the settings of the YAML file.
+func readFile(prefix string, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read the settings: %w", err)
+	}
+
+	var settings map[string]interface{}
+
+	err = yaml.Unmarshal(data, &settings)
+	if err != nil {
+		return fmt.Errorf("failed to read the settings of %s: %w", path, err)
+	}
+
+	vars := make(map[string]string)
+	for key, value := range settings {
+		flatten(vars, prefix+"_"+strings.ToUpper(key), value)
+	}
+
+	for name, value := range vars {
+		if _, ok := os.LookupEnv(name); !ok {
+			os.Setenv(name, value)
+		}
+	}
+
+	return nil
+}
+
+// flatten adds the variables of the setting to vars, the ones of its fields when it is a struct.
+// A list is the comma separated list envconfig reads.
+func flatten(vars map[string]string, name string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		for key, field := range v {
+			flatten(vars, name+"_"+strings.ToUpper(fmt.Sprint(key)), field)
+		}
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+
+		vars[name] = strings.Join(items, ",")
+	default:
+		vars[name] = fmt.Sprint(v)
+	}
+}
+
+// validate calls the Validator of the struct fields of the settings, then the one of the settings.
+// The errors are prefixed with the variables of the settings that failed.
+func validate(v reflect.Value, name string) error {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if !f.CanSet() || f.Kind() != reflect.Struct {
+			continue
+		}
+
+		err := validate(f.Addr(), name+"_"+strings.ToUpper(v.Type().Field(i).Name))
+		if err != nil {
+			return err
+		}
+	}
+
+	if validator, ok := v.Addr().Interface().(Validator); ok {
+		err := validator.Validate()
+		if err != nil {
+			return fmt.Errorf("invalid %s settings: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type settings struct {
+	Broker   Broker
+	Database Database
+	Tenants  []string
+	MaxTodos int64
+}
+
+// environment sets the variables for the test, and unsets the ones of the prefix Load sets once it is done.
+func environment(t *testing.T, prefix string, vars map[string]string) {
+	t.Cleanup(func() {
+		os.Unsetenv(FileVar)
+		for _, env := range os.Environ() {
+			name := strings.SplitN(env, "=", 2)[0]
+			if strings.HasPrefix(name, prefix+"_") {
+				os.Unsetenv(name)
+			}
+		}
+	})
+
+	for name, value := range vars {
+		os.Setenv(name, value)
+	}
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestReadSecrets(t *testing.T) {
+	password := writeFile(t, "password", "s3cret\r\n")
+
+	environment(t, "SECRETS", map[string]string{
+		"SECRETS_DATABASE_PASSWORD_FILE": password,
+		"OTHER_BROKER_PASSWORD_FILE":     password,
+	})
+	defer os.Unsetenv("OTHER_BROKER_PASSWORD_FILE")
+
+	if err := readSecrets("SECRETS"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := os.Getenv("SECRETS_DATABASE_PASSWORD"); got != "s3cret" {
+		t.Errorf("SECRETS_DATABASE_PASSWORD = %q, want the trimmed content of the file", got)
+	}
+
+	if _, ok := os.LookupEnv("OTHER_BROKER_PASSWORD"); ok {
+		t.Error("the secret of another service is read")
+	}
+}
+
+func TestReadSecretsErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+	}{
+		{"both set", map[string]string{
+			"BOTH_DATABASE_PASSWORD":      "s3cret",
+			"BOTH_DATABASE_PASSWORD_FILE": writeFile(t, "password", "s3cret"),
+		}},
+		{"missing file", map[string]string{
+			"MISSING_DATABASE_PASSWORD_FILE": filepath.Join(t.TempDir(), "none"),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefix := strings.ToUpper(strings.SplitN(tt.name, " ", 2)[0])
+			environment(t, prefix, tt.vars)
+
+			if err := readSecrets(prefix); err == nil {
+				t.Error("readSecrets() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	vars := make(map[string]string)
+	flatten(vars, "P_BROKER", map[interface{}]interface{}{
+		"url": "amqp://rabbitmq:5672/",
+		"tls": map[interface{}]interface{}{"enabled": true, "caFile": nil},
+	})
+	flatten(vars, "P_TENANTS", []interface{}{"acme", "globex"})
+	flatten(vars, "P_MAXTODOS", 10)
+
+	want := map[string]string{
+		"P_BROKER_URL":         "amqp://rabbitmq:5672/",
+		"P_BROKER_TLS_ENABLED": "true",
+		"P_TENANTS":            "acme,globex",
+		"P_MAXTODOS":           "10",
+	}
+
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("flatten() = %v, want %v", vars, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := writeFile(t, "settings.yml", `
+broker:
+  url: amqp://rabbitmq:5672/
+  user: todo
+database:
+  uri: mongodb://mongo:27017/
+  connectTimeout: 5s
+tenants:
+  - acme
+  - globex
+maxTodos: 10
+`)
+
+	environment(t, "LOAD", map[string]string{
+		FileVar:         file,
+		"LOAD_MAXTODOS": "20",
+	})
+
+	var s settings
+	if err := Load("load", &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Broker.Url != "amqp://rabbitmq:5672/" || s.Broker.User != "todo" {
+		t.Errorf("Broker = %+v, want the one of the file", s.Broker)
+	}
+
+	if s.Database.ConnectTimeout.Seconds() != 5 {
+		t.Errorf("ConnectTimeout = %s, want the one of the file", s.Database.ConnectTimeout)
+	}
+
+	if !reflect.DeepEqual(s.Tenants, []string{"acme", "globex"}) {
+		t.Errorf("Tenants = %v, want the list of the file", s.Tenants)
+	}
+
+	if s.MaxTodos != 20 {
+		t.Errorf("MaxTodos = %d, want the one of the environment over the file", s.MaxTodos)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		vars map[string]string
+	}{
+		{"valid", nil},
+		{"broker url", map[string]string{"INVALID_BROKER_URL": "http://rabbitmq:5672/"}},
+		{"database uri", map[string]string{"INVALID_DATABASE_URI": "postgres://postgres:5432/"}},
+		{"no broker user", map[string]string{"INVALID_BROKER_USER": ""}},
+		{"password without user", map[string]string{"INVALID_DATABASE_PASSWORD": "s3cret"}},
+		{"amqp with TLS", map[string]string{"INVALID_BROKER_TLS_ENABLED": "true"}},
+		{"certificate without key", map[string]string{
+			"INVALID_DATABASE_TLS_ENABLED":  "true",
+			"INVALID_DATABASE_TLS_CERTFILE": "/etc/todo/client.pem",
+		}},
+		{"missing CA file", map[string]string{
+			"INVALID_DATABASE_TLS_ENABLED": "true",
+			"INVALID_DATABASE_TLS_CAFILE":  "/nonexistent/ca.pem",
+		}},
+		{"certificates without TLS", map[string]string{"INVALID_DATABASE_TLS_CAFILE": "/etc/todo/ca.pem"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars := map[string]string{
+				"INVALID_BROKER_URL":   "amqp://rabbitmq:5672/",
+				"INVALID_BROKER_USER":  "todo",
+				"INVALID_DATABASE_URI": "mongodb://mongo:27017/",
+			}
+			for name, value := range tt.vars {
+				vars[name] = value
+			}
+
+			environment(t, "INVALID", vars)
+
+			var s settings
+			err := Load("invalid", &s)
+			if tt.vars == nil && err != nil {
+				t.Errorf("Load() = %v, want the settings every other case breaks to be valid", err)
+			}
+
+			if tt.vars != nil && err == nil {
+				t.Error("Load() = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestTLSValidate(t *testing.T) {
+	notPem := writeFile(t, "ca.pem", "not a certificate")
+
+	tests := []struct {
+		name    string
+		tls     TLS
+		wantErr bool
+	}{
+		{"disabled", TLS{}, false},
+		{"system CAs", TLS{Enabled: true}, false},
+		{"certificate without key", TLS{Enabled: true, CertFile: "client.pem"}, true},
+		{"key without certificate", TLS{Enabled: true, KeyFile: "client.key"}, true},
+		{"missing CA file", TLS{Enabled: true, CaFile: filepath.Join(t.TempDir(), "none")}, true},
+		{"no certificate in the CA file", TLS{Enabled: true, CaFile: notPem}, true},
+		{"missing certificate", TLS{Enabled: true, CertFile: notPem + ".missing", KeyFile: notPem}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tls.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && tt.tls.Enabled != (tt.tls.Config() != nil) {
+				t.Errorf("Config() = %v with TLS enabled %v", tt.tls.Config(), tt.tls.Enabled)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Broker is the RabbitMQ broker the services send their messages through. The credentials are set apart
+// from the url, e.g. in Docker secrets.
+type Broker struct {
+	Url      string // e.g. amqp://rabbitmq:5672/ or amqps://broker.example.com:5671/vhost
+	User     string
+	Password string
+	TLS      TLS
+}
+
+func (b *Broker) Validate() error {
+	u, err := parseUrl(b.Url, "amqp", "amqps")
+	if err != nil {
+		return err
+	}
+
+	if b.TLS.Enabled && u.Scheme != "amqps" {
+		return fmt.Errorf("the url %s of a broker with TLS has to be amqps", b.Url)
+	}
+
+	// the broker would be connected to as guest
+	if b.User == "" && u.User == nil {
+		return errors.New("the user of the broker is not set")
+	}
+
+	return checkUser(b.User, b.Password)
+}
+
+// URL returns the url of the broker, with the credentials of the user.
+func (b *Broker) URL() string {
+	return withUser(b.Url, b.User, b.Password)
+}
+
+// Database is the MongoDB deployment of the services. The credentials are set apart from the uri, e.g. in
+// Docker secrets.
+type Database struct {
+	Uri            string // e.g. mongodb://mongo:27017/?authSource=admin&replicaSet=rs0
+	User           string
+	Password       string
+	ConnectTimeout time.Duration `default:"10s"`
+	TLS            TLS
+}
+
+func (d *Database) Validate() error {
+	_, err := parseUrl(d.Uri, "mongodb", "mongodb+srv")
+	if err != nil {
+		return err
+	}
+
+	if d.ConnectTimeout <= 0 {
+		return fmt.Errorf("the connect timeout has to be positive, got %s", d.ConnectTimeout)
+	}
+
+	return checkUser(d.User, d.Password)
+}
+
+// URI returns the uri of the database, with the credentials of the user.
+func (d *Database) URI() string {
+	return withUser(d.Uri, d.User, d.Password)
+}
+
+// HTTP is the server of the API of a controller.
+type HTTP struct {
+	Port         int
+	ReadTimeout  time.Duration `default:"30s"`
+	WriteTimeout time.Duration `default:"30s"` // the websockets aren't bound by it
+	IdleTimeout  time.Duration `default:"2m"`
+}
+
+func (h *HTTP) Validate() error {
+	if h.Port < 1 || h.Port > 65535 {
+		return fmt.Errorf("invalid port %d", h.Port)
+	}
+
+	if h.ReadTimeout < 0 || h.WriteTimeout < 0 || h.IdleTimeout < 0 {
+		return errors.New("the timeouts can't be negative")
+	}
+
+	return nil
+}
+
+// Server returns the server of the handler listening on the port.
+func (h *HTTP) Server(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         ":" + strconv.Itoa(h.Port),
+		Handler:      handler,
+		ReadTimeout:  h.ReadTimeout,
+		WriteTimeout: h.WriteTimeout,
+		IdleTimeout:  h.IdleTimeout,
+	}
+}
+
+// TLS secures the connections to a server. Its certificate is verified with the CaFile certificates,
+// the ones of the system when it is not set, and the client presents the certificate of CertFile and
+// KeyFile when they are.
+type TLS struct {
+	Enabled            bool
+	CaFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool // for the self-signed certificates of a development stack only
+
+	config *tls.Config
+}
+
+// Validate loads the certificates, see Config.
+func (t *TLS) Validate() error {
+	if !t.Enabled {
+		if t.CaFile != "" || t.CertFile != "" || t.KeyFile != "" {
+			return errors.New("certificates are set but TLS is not enabled")
+		}
+
+		return nil
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("the certificate and the key of the client go together")
+	}
+
+	config := &tls.Config{InsecureSkipVerify: t.InsecureSkipVerify}
+
+	if t.CaFile != "" {
+		pem, err := ioutil.ReadFile(t.CaFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the CA certificates: %w", err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificate found in %s", t.CaFile)
+		}
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load the certificate of the client: %w", err)
+		}
+
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	t.config = config
+	return nil
+}
+
+// Config returns the configuration of the connections built by Validate, which Load calls,
+// nil when TLS is not enabled.
+func (t *TLS) Config() *tls.Config {
+	return t.config
+}
+
+// parseUrl fails unless the url is set with one of the schemes.
+func parseUrl(rawUrl string, schemes ...string) (*url.URL, error) {
+	if rawUrl == "" {
+		return nil, errors.New("the url is not set")
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return u, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the url %s isn't one of %v", rawUrl, schemes)
+}
+
+func checkUser(user string, password string) error {
+	if user == "" && password != "" {
+		return errors.New("the password is set without its user")
+	}
+
+	return nil
+}
+
+// withUser returns the url with the credentials of the user, the ones of the url when the user isn't set.
+func withUser(rawUrl string, user string, password string) string {
+	u, err := url.Parse(rawUrl)
+	if err != nil || user == "" {
+		return rawUrl
+	}
+
+	u.User = url.UserPassword(user, password)
+	return u.String()
+}
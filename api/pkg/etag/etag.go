@@ -0,0 +1,102 @@
+// Package etag converts the versions of the todos to entity tags and back.
+package etag
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNoMatch is returned by IfMatch when no version can match the header, e.g. it only has weak tags.
+var ErrNoMatch = errors.New("the If-Match header can't match any version")
+
+// Format returns the strong entity tag of a version.
+func Format(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// Conditions is a parsed If-Match or If-None-Match header.
+type Conditions struct {
+	Any      bool    // the header is *
+	Versions []int64 // versions of the strong entity tags
+	Weak     []int64 // versions of the weak entity tags
+}
+
+// Parse reads the comma separated list of entity tags of an If-Match or If-None-Match header.
+func Parse(header string) (*Conditions, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return nil, nil
+	}
+
+	if header == "*" {
+		return &Conditions{Any: true}, nil
+	}
+
+	var conditions Conditions
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		weak := strings.HasPrefix(tag, "W/")
+		if weak {
+			tag = tag[2:]
+		}
+
+		value, err := strconv.Unquote(tag)
+		if err != nil || !strings.HasPrefix(tag, `"`) {
+			return nil, fmt.Errorf("invalid entity tag %s", tag)
+		}
+
+		version, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			// not one of ours, it can't match any todo
+			continue
+		}
+
+		if weak {
+			conditions.Weak = append(conditions.Weak, version)
+		} else {
+			conditions.Versions = append(conditions.Versions, version)
+		}
+	}
+
+	return &conditions, nil
+}
+
+// Matches compares the version with the tags using the weak comparison of If-None-Match.
+func (c *Conditions) Matches(version int64) bool {
+	if c.Any {
+		return true
+	}
+
+	for _, v := range c.Versions {
+		if v == version {
+			return true
+		}
+	}
+
+	for _, v := range c.Weak {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IfMatch returns the versions allowed by an If-Match header, which uses the strong comparison.
+// It returns none when the header is missing or allows any version.
+func IfMatch(header string) ([]int64, error) {
+	conditions, err := Parse(header)
+	if err != nil || conditions == nil || conditions.Any {
+		return nil, err
+	}
+
+	if len(conditions.Versions) == 0 {
+		return nil, ErrNoMatch
+	}
+
+	return conditions.Versions, nil
+}
@@ -0,0 +1,94 @@
+package etag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	if got := Format(42); got != `"42"` {
+		t.Errorf("Format(42) = %s, want \"42\"", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    *Conditions
+		wantErr bool
+	}{
+		{"missing", "", nil, false},
+		{"blank", "  ", nil, false},
+		{"any", "*", &Conditions{Any: true}, false},
+		{"strong", `"3"`, &Conditions{Versions: []int64{3}}, false},
+		{"weak", `W/"3"`, &Conditions{Weak: []int64{3}}, false},
+		{"list", ` "1", W/"2" ,"3"`, &Conditions{Versions: []int64{1, 3}, Weak: []int64{2}}, false},
+		{"foreign tag", `"abc", "4"`, &Conditions{Versions: []int64{4}}, false},
+		{"only foreign tags", `"abc"`, &Conditions{}, false},
+		{"unquoted", `3`, nil, true},
+		{"backquoted", "`3`", nil, true},
+		{"unterminated", `"3`, nil, true},
+		{"empty tag", `"1",`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.header)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, want error %v", tt.header, err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	c := &Conditions{Versions: []int64{1}, Weak: []int64{2}}
+
+	for version, want := range map[int64]bool{1: true, 2: true, 3: false} {
+		if got := c.Matches(version); got != want {
+			t.Errorf("Matches(%d) = %v, want %v", version, got, want)
+		}
+	}
+
+	if !(&Conditions{Any: true}).Matches(7) {
+		t.Error("* doesn't match every version")
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    []int64
+		wantErr error
+	}{
+		{"missing", "", nil, nil},
+		{"any", "*", nil, nil},
+		{"strong", `"1", "2"`, []int64{1, 2}, nil},
+		{"strong and weak", `"1", W/"2"`, []int64{1}, nil},
+		{"weak only", `W/"1"`, nil, ErrNoMatch},
+		{"foreign only", `"abc"`, nil, ErrNoMatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := IfMatch(tt.header)
+			if err != tt.wantErr {
+				t.Fatalf("IfMatch(%q) error = %v, want %v", tt.header, err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("IfMatch(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := IfMatch(`1`); err == nil || err == ErrNoMatch {
+		t.Errorf("IfMatch(1) error = %v, want a malformed header", err)
+	}
+}
@@ -0,0 +1,13 @@
+module todo-go/pkg
+
+go 1.16
+
+require (
+	github.com/golang-jwt/jwt/v4 v4.5.2
+	github.com/google/uuid v1.3.0
+	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/streadway/amqp v1.0.0
+	github.com/teambition/rrule-go v1.8.2
+	go.mongodb.org/mongo-driver v1.3.1
+	gopkg.in/yaml.v2 v2.4.0
+)
@@ -0,0 +1,397 @@
+package messaging
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Number of idle channels kept open by a client when none is given.
+const DefaultPoolSize = 8
+
+// How long a client waits for the broker to confirm a published command.
+const ConfirmTimeout = 5 * time.Second
+
+// Client publishes requests to the DAO queues over a long-lived connection.
+// The connection is opened on first use and reopened after the broker closed it.
+type Client struct {
+	url       string
+	tlsConfig *tls.Config
+	timeout   time.Duration
+	poolSize  int
+
+	mu      sync.Mutex
+	current *session
+}
+
+// session is everything that lives and dies with one connection to the broker.
+type session struct {
+	conn *amqp.Connection
+	pool chan *confirmChannel
+
+	// channel consuming the direct reply-to pseudo queue, the requests
+	// awaiting a reply have to be published on it as well
+	rpc *amqp.Channel
+
+	mu       sync.Mutex
+	waiters  map[string]chan amqp.Delivery
+	declared map[string]bool
+}
+
+// confirmChannel is a channel in confirm mode, each publish waits for the broker to confirm it.
+type confirmChannel struct {
+	*amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+func (ch *confirmChannel) publish(queue string, msg amqp.Publishing) error {
+	err := ch.Publish(
+		"",    // exchange
+		queue, // routing key
+		false, // mandatory
+		false, // immediate
+		msg)
+
+	if err != nil {
+		return err
+	}
+
+	select {
+	case confirm, ok := <-ch.confirms:
+		if !ok {
+			return ErrClosed
+		}
+
+		if !confirm.Ack {
+			return ErrNotConfirmed
+		}
+
+		return nil
+	case <-time.After(ConfirmTimeout):
+		return ErrNotConfirmed
+	}
+}
+
+// NewClient creates a client for the broker at url. Call gives up waiting for a reply after
+// timeout, a zero timeout makes it wait until its context is done. At most poolSize idle
+// channels are kept open for publishing.
+func NewClient(url string, timeout time.Duration, poolSize int) *Client {
+	if poolSize <= 0 {
+		poolSize = DefaultPoolSize
+	}
+
+	return &Client{url: url, timeout: timeout, poolSize: poolSize}
+}
+
+// TLS makes the client connect to an amqps url with the configuration, the default one when it is nil.
+func (c *Client) TLS(config *tls.Config) *Client {
+	c.tlsConfig = config
+	return c
+}
+
+func (c *Client) session() (*session, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current != nil && !c.current.conn.IsClosed() {
+		return c.current, nil
+	}
+
+	conn, err := dial(c.url, c.tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the message broker: %w", err)
+	}
+
+	rpc, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel for message broker connection: %w", err)
+	}
+
+	replies, err := rpc.Consume(
+		DirectReplyTo, // queue
+		"",            // consumer
+		true,          // auto-ack
+		false,         // exclusive
+		false,         // no-local
+		false,         // no-wait
+		nil,           // args
+	)
+
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to register the reply queue consumer: %w", err)
+	}
+
+	s := &session{
+		conn:     conn,
+		pool:     make(chan *confirmChannel, c.poolSize),
+		rpc:      rpc,
+		waiters:  make(map[string]chan amqp.Delivery),
+		declared: make(map[string]bool),
+	}
+
+	go s.dispatch(replies)
+
+	c.current = s
+	return s, nil
+}
+
+// dispatch hands every reply to the request waiting for its correlation id.
+func (s *session) dispatch(replies <-chan amqp.Delivery) {
+	for d := range replies {
+		s.mu.Lock()
+		waiter, ok := s.waiters[d.CorrelationId]
+		delete(s.waiters, d.CorrelationId)
+		s.mu.Unlock()
+
+		if !ok {
+			log.Printf("Discarding reply %s, nobody is waiting for it \n", d.CorrelationId)
+			continue
+		}
+
+		waiter <- d
+	}
+
+	log.Println("The reply queue consumer was closed")
+
+	// wake up the pending requests, their replies are lost with the connection
+	s.mu.Lock()
+	for corrId, waiter := range s.waiters {
+		close(waiter)
+		delete(s.waiters, corrId)
+	}
+	s.mu.Unlock()
+
+	s.conn.Close()
+}
+
+func (s *session) wait(corrId string) <-chan amqp.Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	waiter := make(chan amqp.Delivery, 1)
+	s.waiters[corrId] = waiter
+	return waiter
+}
+
+func (s *session) forget(corrId string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.waiters, corrId)
+}
+
+func (s *session) acquire() (*confirmChannel, error) {
+	select {
+	case ch := <-s.pool:
+		return ch, nil
+	default:
+	}
+
+	ch, err := s.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel for message broker connection: %w", err)
+	}
+
+	err = ch.Confirm(false)
+	if err != nil {
+		ch.Close()
+		return nil, fmt.Errorf("failed to put the channel in confirm mode: %w", err)
+	}
+
+	return &confirmChannel{ch, ch.NotifyPublish(make(chan amqp.Confirmation, 1))}, nil
+}
+
+// release puts a healthy channel back in the pool, or closes it when the pool is full.
+func (s *session) release(ch *confirmChannel, err error) {
+	if err != nil {
+		// a failed publish may have closed the channel or left a confirmation behind, don't hand it out again
+		ch.Close()
+		return
+	}
+
+	select {
+	case s.pool <- ch:
+	default:
+		ch.Close()
+	}
+}
+
+// ensureQueue declares the durable queue the first time a message is published to it,
+// so nothing is dropped if the consumer didn't declare it yet.
+func (s *session) ensureQueue(ch *confirmChannel, name string, args amqp.Table) error {
+	s.mu.Lock()
+	declared := s.declared[name]
+	s.mu.Unlock()
+
+	if declared {
+		return nil
+	}
+
+	_, err := declareQueue(ch.Channel, name, args)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.declared[name] = true
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Close closes the connection to the broker, failing the requests still awaiting a reply.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.current == nil {
+		return nil
+	}
+
+	err := c.current.conn.Close()
+	c.current = nil
+
+	if err != nil && err != amqp.ErrClosed {
+		return fmt.Errorf("failed to close the connection to the message broker: %w", err)
+	}
+
+	return nil
+}
+
+// Call publishes body to the queue and waits for the DAO reply, returning its result.
+// It returns a TimeoutError when the deadline is exceeded and the context error when it is cancelled.
+// Headers are sent along with the request, e.g. its UserIdHeader.
+func (c *Client) Call(ctx context.Context, queue string, body []byte, headers map[string]string) (res []byte, err error) {
+	s, err := c.session()
+	if err != nil {
+		return nil, err
+	}
+
+	corrId := NewCorrelationId()
+
+	// stop waiting as soon as we give up on the reply, e.g. when the http client disconnected
+	waiter := s.wait(corrId)
+	defer s.forget(corrId)
+
+	err = s.rpc.Publish(
+		"",    // exchange
+		queue, // routing key
+		false, // mandatory
+		false, // immediate
+		amqp.Publishing{
+			Headers:       headerTable(headers),
+			ContentType:   "text/plain",
+			CorrelationId: corrId,
+			ReplyTo:       DirectReplyTo,
+			Body:          body,
+		})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish the message to the queue: %w", err)
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	select {
+	case d, ok := <-waiter:
+		if !ok {
+			return nil, ErrClosed
+		}
+
+		log.Printf("Reply %s received from the DAO \n", corrId)
+
+		return DecodeResult(d.Body)
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &TimeoutError{corrId}
+		}
+
+		return nil, fmt.Errorf("request %s: %w", corrId, ctx.Err())
+	}
+}
+
+// SendCommand registers a command in the status queue and publishes it to the DAO queue,
+// which will reply to the status queue. Both messages are persistent and confirmed by the broker
+// before it returns the correlation id of the command. The DAO queue is declared as a command
+// queue, so the DAO consuming it has to retry its failed requests, see Server.Retry.
+// The command has the type of its operation, headers are sent along with it and its registration,
+// e.g. its IdempotencyKeyHeader or UserIdHeader.
+func (c *Client) SendCommand(queue string, statusQueue string, operation string, body []byte, headers map[string]string) (corrId string, err error) {
+	s, err := c.session()
+	if err != nil {
+		return "", err
+	}
+
+	ch, err := s.acquire()
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { s.release(ch, err) }()
+
+	err = s.ensureQueue(ch, statusQueue, nil)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.ensureQueue(ch, queue, commandQueueArgs(queue))
+	if err != nil {
+		return "", err
+	}
+
+	corrId = NewCorrelationId()
+
+	err = ch.publish(statusQueue, amqp.Publishing{
+		Headers:       headerTable(headers),
+		ContentType:   "text/plain",
+		DeliveryMode:  amqp.Persistent,
+		Type:          AcceptedType,
+		CorrelationId: corrId,
+		Body:          []byte(operation),
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to register the command: %w", err)
+	}
+
+	err = ch.publish(queue, amqp.Publishing{
+		Headers:       headerTable(headers),
+		ContentType:   "text/plain",
+		DeliveryMode:  amqp.Persistent,
+		Type:          operation,
+		CorrelationId: corrId,
+		ReplyTo:       statusQueue,
+		Body:          body,
+	})
+
+	if err != nil {
+		return "", fmt.Errorf("failed to publish the message to the queue: %w", err)
+	}
+
+	return corrId, nil
+}
+
+// headerTable returns the headers of a message, none when there is no header.
+func headerTable(headers map[string]string) amqp.Table {
+	if len(headers) == 0 {
+		return nil
+	}
+
+	table := make(amqp.Table, len(headers))
+	for name, value := range headers {
+		table[name] = value
+	}
+
+	return table
+}
@@ -0,0 +1,134 @@
+package messaging
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+
+	"github.com/streadway/amqp"
+)
+
+// DeliveryFunc handles a message consumed by a Consumer.
+type DeliveryFunc func(d amqp.Delivery)
+
+// Consumer hands the messages of a queue, or of a fanout exchange, to a function, acking each of them
+// once it returned, so the messages in flight when the service stops are redelivered. Unlike a Server
+// it doesn't reply.
+type Consumer struct {
+	url       string
+	tlsConfig *tls.Config
+	queue     string
+	exchange  string
+	consumer  string
+	handle    DeliveryFunc
+
+	state int32
+}
+
+// NewConsumer consumes the durable queue, e.g. the command status queue.
+func NewConsumer(url string, queue string, consumer string, handle DeliveryFunc) *Consumer {
+	return &Consumer{
+		url:      url,
+		queue:    queue,
+		consumer: consumer,
+		handle:   handle,
+	}
+}
+
+// NewSubscriber consumes the messages published to the fanout exchange from then on, e.g. the todo events,
+// through an exclusive queue. The messages published while it reconnects are missed.
+func NewSubscriber(url string, exchange string, consumer string, handle DeliveryFunc) *Consumer {
+	return &Consumer{
+		url:      url,
+		exchange: exchange,
+		consumer: consumer,
+		handle:   handle,
+	}
+}
+
+// TLS makes the consumer connect to an amqps url with the configuration, the default one when it is nil.
+func (c *Consumer) TLS(config *tls.Config) *Consumer {
+	c.tlsConfig = config
+	return c
+}
+
+func (c *Consumer) State() State {
+	return State(atomic.LoadInt32(&c.state))
+}
+
+func (c *Consumer) setState(st State) {
+	atomic.StoreInt32(&c.state, int32(st))
+}
+
+// ListenAndServe consumes forever, reconnecting to the broker as Server.ListenAndServe does.
+func (c *Consumer) ListenAndServe() {
+	reconnect(c.name(), c.consume, c.setState)
+}
+
+func (c *Consumer) name() string {
+	if c.exchange != "" {
+		return c.exchange
+	}
+
+	return c.queue
+}
+
+// consume hands the messages to the function over a new connection until it is closed.
+func (c *Consumer) consume(connected func()) error {
+	conn, err := DialTLS(c.url, c.tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	closed := conn.conn.NotifyClose(make(chan *amqp.Error, 1))
+	channelClosed := conn.channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	var q amqp.Queue
+	if c.exchange != "" {
+		q, err = conn.bindQueue(c.exchange)
+	} else {
+		q, err = conn.declareQueue(c.queue)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	msgs, err := conn.channel.Consume(
+		q.Name,           // queue
+		c.consumer,       // consumer
+		false,            // auto-ack
+		c.exchange != "", // exclusive, as the queue bound to the exchange
+		false,            // no-local
+		false,            // no-wait
+		nil,              // args
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to register a consumer to %s: %w", c.name(), err)
+	}
+
+	connected()
+
+	log.Printf("Listening for messages on %s...", c.name())
+
+	for {
+		select {
+		case d, ok := <-msgs:
+			if !ok {
+				return errors.New("the consumer was closed by the message broker")
+			}
+
+			c.handle(d)
+			d.Ack(false)
+		case amqpErr := <-closed:
+			return fmt.Errorf("connection closed: %v", amqpErr)
+		case amqpErr := <-channelClosed:
+			return fmt.Errorf("channel closed: %v", amqpErr)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Headers set on the messages moved to a dead-letter queue.
+const (
+	AttemptsHeader      = "x-attempts"
+	LastErrorHeader     = "x-last-error"
+	OriginalQueueHeader = "x-original-queue"
+)
+
+// Defaults used by Server.Retry when given zero values.
+const (
+	DefaultMaxAttempts = 5
+	DefaultRetryDelay  = 10 * time.Second
+)
+
+// RetryQueue is where the failed requests of a queue wait before being consumed again.
+func RetryQueue(queue string) string {
+	return queue + ".retry"
+}
+
+// DeadLetterQueue is where the requests of a queue end up after failing every attempt.
+func DeadLetterQueue(queue string) string {
+	return queue + ".dead"
+}
+
+// commandQueueArgs routes the messages rejected from a command queue to its retry queue.
+// Everyone declaring a command queue has to use the same arguments.
+func commandQueueArgs(queue string) amqp.Table {
+	return amqp.Table{
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": RetryQueue(queue),
+	}
+}
+
+// retryQueueArgs sends the messages back to the command queue once they waited for delay.
+func retryQueueArgs(queue string, delay time.Duration) amqp.Table {
+	return amqp.Table{
+		"x-message-ttl":             int64(delay / time.Millisecond),
+		"x-dead-letter-exchange":    "",
+		"x-dead-letter-routing-key": queue,
+	}
+}
+
+// declareCommandQueues declares the command queue along with its retry and dead-letter queues.
+func (c *Connection) declareCommandQueues(queue string, delay time.Duration) (amqp.Queue, error) {
+	_, err := declareQueue(c.channel, RetryQueue(queue), retryQueueArgs(queue, delay))
+	if err != nil {
+		return amqp.Queue{}, err
+	}
+
+	_, err = declareQueue(c.channel, DeadLetterQueue(queue), nil)
+	if err != nil {
+		return amqp.Queue{}, err
+	}
+
+	return declareQueue(c.channel, queue, commandQueueArgs(queue))
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// Permanent marks an error that retrying the request won't fix. The request is answered
+// right away instead of retried.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &permanentError{err}
+}
+
+// IsPermanent tells whether retrying the request can't fix err: errors marked Permanent and
+// Errors other than internal ones.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return true
+	}
+
+	var e *Error
+	return errors.As(err, &e) && e.Code != CodeInternal
+}
+
+// attempts counts how many times the delivery was already rejected from queue,
+// using the x-death header maintained by the broker.
+func attempts(d amqp.Delivery, queue string) int64 {
+	deaths, ok := d.Headers["x-death"].([]interface{})
+	if !ok {
+		return 0
+	}
+
+	for _, death := range deaths {
+		table, ok := death.(amqp.Table)
+		if !ok || table["queue"] != queue || table["reason"] != "rejected" {
+			continue
+		}
+
+		if count, ok := table["count"].(int64); ok {
+			return count
+		}
+	}
+
+	return 0
+}
+
+// requestHeaders copies the headers sent along with the request, e.g. its IdempotencyKeyHeader,
+// leaving out the ones set by the broker and by deadLetter.
+func requestHeaders(d amqp.Delivery) amqp.Table {
+	headers := make(amqp.Table, len(d.Headers))
+	for name, value := range d.Headers {
+		switch name {
+		case "x-death", "x-first-death-exchange", "x-first-death-queue", "x-first-death-reason",
+			AttemptsHeader, LastErrorHeader, OriginalQueueHeader:
+			continue
+		}
+
+		headers[name] = value
+	}
+
+	return headers
+}
+
+// deadLetter copies the delivery to the dead-letter queue of queue.
+func deadLetter(ch *amqp.Channel, d amqp.Delivery, queue string, attempts int64, cause error) error {
+	headers := requestHeaders(d)
+	headers[AttemptsHeader] = attempts
+	headers[LastErrorHeader] = cause.Error()
+	headers[OriginalQueueHeader] = queue
+
+	err := ch.Publish(
+		"",                     // exchange
+		DeadLetterQueue(queue), // routing key
+		false,                  // mandatory
+		false,                  // immediate
+		amqp.Publishing{
+			Headers:       headers,
+			ContentType:   d.ContentType,
+			DeliveryMode:  amqp.Persistent,
+			Type:          d.Type,
+			CorrelationId: d.CorrelationId,
+			ReplyTo:       d.ReplyTo,
+			Timestamp:     time.Now(),
+			Body:          d.Body,
+		})
+
+	if err != nil {
+		return fmt.Errorf("failed to publish the message to the dead-letter queue: %w", err)
+	}
+
+	return nil
+}
+
+// DeadLetter is a request that failed every attempt.
+type DeadLetter struct {
+	CorrelationId string
+	OriginalQueue string
+	Attempts      int64
+	LastError     string
+	DeadAt        time.Time
+	Body          string
+}
+
+func newDeadLetter(d amqp.Delivery) DeadLetter {
+	letter := DeadLetter{
+		CorrelationId: d.CorrelationId,
+		DeadAt:        d.Timestamp,
+		Body:          string(d.Body),
+	}
+
+	letter.OriginalQueue, _ = d.Headers[OriginalQueueHeader].(string)
+	letter.Attempts, _ = d.Headers[AttemptsHeader].(int64)
+	letter.LastError, _ = d.Headers[LastErrorHeader].(string)
+
+	return letter
+}
+
+// InspectDeadLetters returns up to limit messages of the dead-letter queue of queue, leaving them in place.
+func (c *Connection) InspectDeadLetters(queue string, limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+	var last uint64
+
+	for len(letters) < limit {
+		d, ok, err := c.channel.Get(DeadLetterQueue(queue), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the dead-letter queue: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		letters = append(letters, newDeadLetter(d))
+		last = d.DeliveryTag
+	}
+
+	if last > 0 {
+		// put everything back where it was
+		err := c.channel.Nack(last, true, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to requeue the dead letters: %w", err)
+		}
+	}
+
+	return letters, nil
+}
+
+// ReplayDeadLetters moves up to limit messages of the dead-letter queue of queue back to queue,
+// with a fresh count of attempts. It returns the replayed messages.
+func (c *Connection) ReplayDeadLetters(queue string, limit int) ([]DeadLetter, error) {
+	var letters []DeadLetter
+
+	for len(letters) < limit {
+		d, ok, err := c.channel.Get(DeadLetterQueue(queue), false)
+		if err != nil {
+			return letters, fmt.Errorf("failed to read the dead-letter queue: %w", err)
+		}
+
+		if !ok {
+			break
+		}
+
+		err = c.channel.Publish(
+			"",    // exchange
+			queue, // routing key
+			false, // mandatory
+			false, // immediate
+			amqp.Publishing{
+				Headers:       requestHeaders(d),
+				ContentType:   d.ContentType,
+				DeliveryMode:  amqp.Persistent,
+				Type:          d.Type,
+				CorrelationId: d.CorrelationId,
+				ReplyTo:       d.ReplyTo,
+				Body:          d.Body,
+			})
+
+		if err != nil {
+			d.Nack(false, true)
+			return letters, fmt.Errorf("failed to replay the message %s: %w", d.CorrelationId, err)
+		}
+
+		d.Ack(false)
+		letters = append(letters, newDeadLetter(d))
+	}
+
+	return letters, nil
+}
@@ -0,0 +1,91 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/streadway/amqp"
+)
+
+func TestIsPermanent(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"plain error", errors.New("connection refused"), false},
+		{"internal error", NewError(CodeInternal, "boom"), false},
+		{"wrapped internal error", fmt.Errorf("insert: %w", NewError(CodeInternal, "boom")), false},
+		{"invalid error", Invalid("bad body"), true},
+		{"not found error", NotFound("no todo"), true},
+		{"wrapped conflict error", fmt.Errorf("insert: %w", Conflict("duplicate")), true},
+		{"permanent error", Permanent(errors.New("malformed")), true},
+		{"wrapped permanent error", fmt.Errorf("handle: %w", Permanent(errors.New("malformed"))), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsPermanent(tt.err); got != tt.want {
+				t.Errorf("IsPermanent(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPermanentNil(t *testing.T) {
+	if err := Permanent(nil); err != nil {
+		t.Errorf("Permanent(nil) = %v, want nil", err)
+	}
+}
+
+func TestAttempts(t *testing.T) {
+	death := func(queue string, reason string, count interface{}) amqp.Table {
+		return amqp.Table{"queue": queue, "reason": reason, "count": count}
+	}
+
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		want    int64
+	}{
+		{"no headers", nil, 0},
+		{"no x-death", amqp.Table{"x-user-id": "alice"}, 0},
+		{"malformed x-death", amqp.Table{"x-death": "post"}, 0},
+		{"rejected from the queue", amqp.Table{"x-death": []interface{}{death("post", "rejected", int64(2))}}, 2},
+		{"expired from the retry queue only", amqp.Table{"x-death": []interface{}{death("post.retry", "expired", int64(3))}}, 0},
+		{"rejected from another queue", amqp.Table{"x-death": []interface{}{death("patch", "rejected", int64(4))}}, 0},
+		{"both deaths", amqp.Table{"x-death": []interface{}{
+			death("post.retry", "expired", int64(3)),
+			death("post", "rejected", int64(3)),
+		}}, 3},
+		{"count of another type", amqp.Table{"x-death": []interface{}{death("post", "rejected", 3)}}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := amqp.Delivery{Headers: tt.headers}
+			if got := attempts(d, "post"); got != tt.want {
+				t.Errorf("attempts() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestHeaders(t *testing.T) {
+	d := amqp.Delivery{Headers: amqp.Table{
+		IdempotencyKeyHeader:  "key",
+		UserIdHeader:          "alice",
+		"x-death":             []interface{}{},
+		"x-first-death-queue": "post",
+		AttemptsHeader:        int64(5),
+		LastErrorHeader:       "boom",
+		OriginalQueueHeader:   "post",
+	}}
+
+	headers := requestHeaders(d)
+
+	if len(headers) != 2 || headers[IdempotencyKeyHeader] != "key" || headers[UserIdHeader] != "alice" {
+		t.Errorf("requestHeaders() = %v, want the idempotency key and the user only", headers)
+	}
+}
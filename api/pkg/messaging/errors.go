@@ -0,0 +1,83 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Codes of the errors replied by the DAOs, the controllers map them to HTTP statuses.
+const (
+	CodeInvalid  = "invalid"
+	CodeNotFound = "not_found"
+	CodeConflict = "conflict"
+	// the user can see the todo or the list but isn't allowed to change it
+	CodeForbidden = "forbidden"
+	// a condition set by the client, e.g. If-None-Match, doesn't hold
+	CodePreconditionFailed = "precondition_failed"
+	// the user reached the number of todos they are allowed to have
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeInternal      = "internal"
+)
+
+// Error is the error envelope of a Result. The client returns it as is when the DAO replied with an error.
+type Error struct {
+	Code    string
+	Message string
+	Details map[string]string `json:",omitempty"`
+}
+
+func NewError(code string, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func Invalid(format string, args ...interface{}) *Error {
+	return NewError(CodeInvalid, format, args...)
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return NewError(CodeNotFound, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) *Error {
+	return NewError(CodeConflict, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) *Error {
+	return NewError(CodeForbidden, format, args...)
+}
+
+func PreconditionFailed(format string, args ...interface{}) *Error {
+	return NewError(CodePreconditionFailed, format, args...)
+}
+
+func QuotaExceeded(format string, args ...interface{}) *Error {
+	return NewError(CodeQuotaExceeded, format, args...)
+}
+
+// WithDetail adds a detail to the error, e.g. the id of the todo it is about.
+func (e *Error) WithDetail(name string, value string) *Error {
+	if e.Details == nil {
+		e.Details = make(map[string]string)
+	}
+
+	e.Details[name] = value
+	return e
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// InternalMessage is the message of the internal errors replied to the controllers. The errors themselves
+// may name the hosts, databases or indexes of the deployment, the DAOs only log them.
+const InternalMessage = "internal error"
+
+// AsError returns the Error wrapped by err, or an internal Error with InternalMessage.
+func AsError(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	return NewError(CodeInternal, InternalMessage)
+}
@@ -0,0 +1,203 @@
+// Package messaging holds the RabbitMQ plumbing shared by the controllers and the DAOs:
+// an RPC client to send requests and await their replies, and a server that consumes
+// requests, calls a handler and replies with a Result.
+package messaging
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/streadway/amqp"
+)
+
+// Pseudo queue used to receive RPC replies without declaring a queue.
+const DirectReplyTo = "amq.rabbitmq.reply-to"
+
+// Message type used by the controllers to register a command in the command status queue.
+const AcceptedType = "accepted"
+
+// Header carrying the key a client sent to make its command safe to retry.
+const IdempotencyKeyHeader = "x-idempotency-key"
+
+// Header carrying the user a command is sent on behalf of, the DAOs record them as the actor of the changes.
+const UserIdHeader = "x-user-id"
+
+// Header carrying the tenant a message is sent for, the DAOs use the databases of the tenant.
+const TenantHeader = "x-tenant-id"
+
+// Header carrying the users allowed to see an event, comma separated, see Server.Audience.
+const AudienceHeader = "x-audience"
+
+var ErrTimeout = errors.New("timed out waiting for the reply")
+var ErrClosed = errors.New("the connection to the message broker was closed")
+var ErrNotConfirmed = errors.New("the message broker did not confirm the message")
+
+// Result is the reply sent by the DAOs for every request they consume.
+type Result struct {
+	Err    *Error `json:",omitempty"`
+	Result string // result in json
+}
+
+// TimeoutError is returned by the client when no reply arrived before the request deadline.
+type TimeoutError struct {
+	CorrelationId string
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("request %s: %s", e.CorrelationId, ErrTimeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return ErrTimeout
+}
+
+func NewCorrelationId() string {
+	return uuid.New().String()
+}
+
+func dial(url string, config *tls.Config) (*amqp.Connection, error) {
+	if config != nil {
+		return amqp.DialTLS(url, config)
+	}
+
+	return amqp.Dial(url)
+}
+
+// Connection is a connection to the broker with a single channel opened on it.
+type Connection struct {
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func Dial(url string) (*Connection, error) {
+	return DialTLS(url, nil)
+}
+
+// DialTLS connects to an amqps url with the TLS configuration, the default one when it is nil.
+func DialTLS(url string, config *tls.Config) (*Connection, error) {
+	conn, err := dial(url, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the message broker: %w", err)
+	}
+
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel for message broker connection: %w", err)
+	}
+
+	return &Connection{conn, ch}, nil
+}
+
+func (c *Connection) Channel() *amqp.Channel {
+	return c.channel
+}
+
+func (c *Connection) Close() (err error) {
+	err = c.channel.Close()
+	if err != nil {
+		c.conn.Close()
+		return fmt.Errorf("failed to close the channel to the message broker: %w", err)
+	}
+
+	err = c.conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close the connection to the message broker: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Connection) declareQueue(name string) (amqp.Queue, error) {
+	return declareQueue(c.channel, name, nil)
+}
+
+// declareQueue declares a durable queue, so the messages published as persistent survive a broker restart.
+func declareQueue(ch *amqp.Channel, name string, args amqp.Table) (amqp.Queue, error) {
+	q, err := ch.QueueDeclare(
+		name,  // name
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		args,  // arguments
+	)
+
+	if err != nil {
+		return q, fmt.Errorf("failed to declare the queue %q: %w", name, err)
+	}
+
+	return q, nil
+}
+
+func (c *Connection) declareExchange(name string) error {
+	err := c.channel.ExchangeDeclare(
+		name,     // name
+		"fanout", // type
+		true,     // durable
+		false,    // auto-deleted
+		false,    // internal
+		false,    // no-wait
+		nil,      // arguments
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to declare the exchange %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// bindQueue binds a new exclusive queue to the fanout exchange, deleted along with the connection.
+func (c *Connection) bindQueue(exchange string) (amqp.Queue, error) {
+	err := c.declareExchange(exchange)
+	if err != nil {
+		return amqp.Queue{}, err
+	}
+
+	q, err := c.channel.QueueDeclare(
+		"",    // name
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+
+	if err != nil {
+		return q, fmt.Errorf("failed to declare a queue for the exchange %q: %w", exchange, err)
+	}
+
+	err = c.channel.QueueBind(
+		q.Name,   // queue name
+		"",       // routing key
+		exchange, // exchange
+		false,    // no-wait
+		nil,      // arguments
+	)
+
+	if err != nil {
+		return q, fmt.Errorf("failed to bind the queue to the exchange %q: %w", exchange, err)
+	}
+
+	return q, nil
+}
+
+// DecodeResult parses a reply sent by a DAO, returning its Error if the DAO failed.
+func DecodeResult(body []byte) ([]byte, error) {
+	var data Result
+
+	err := json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the message returned by the DAO: %w", err)
+	}
+
+	if data.Err != nil {
+		return nil, data.Err
+	}
+
+	return []byte(data.Result), nil
+}
@@ -0,0 +1,409 @@
+package messaging
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+// Request is a message consumed by a Server.
+type Request struct {
+	amqp.Delivery
+}
+
+// Decode parses the json body of the request into v, failing with an invalid Error.
+func (r *Request) Decode(v interface{}) error {
+	err := json.Unmarshal(r.Body, v)
+	if err != nil {
+		return Invalid("failed to parse the request body: %s", err)
+	}
+
+	return nil
+}
+
+// IdempotencyKey returns the idempotency key sent along with the request, if any.
+func (r *Request) IdempotencyKey() string {
+	key, _ := r.Headers[IdempotencyKeyHeader].(string)
+	return key
+}
+
+// UserId returns the user the request is sent on behalf of, if any.
+func (r *Request) UserId() string {
+	id, _ := r.Headers[UserIdHeader].(string)
+	return id
+}
+
+// TenantId returns the tenant the request is sent for, if any.
+func (r *Request) TenantId() string {
+	id, _ := r.Headers[TenantHeader].(string)
+	return id
+}
+
+// Operation returns the operation of a command, e.g. patch or delete-item.
+func (r *Request) Operation() string {
+	return r.Type
+}
+
+// HandlerFunc handles a request, returning the value sent back as the json result.
+type HandlerFunc func(req *Request) (interface{}, error)
+
+// Delays between two attempts to reconnect a server to the broker.
+const (
+	MinReconnectDelay = time.Second
+	MaxReconnectDelay = 30 * time.Second
+)
+
+// State of the connection between a server and the broker.
+type State int32
+
+const (
+	StateConnecting State = iota
+	StateConnected
+	StateDisconnected
+)
+
+func (st State) String() string {
+	switch st {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	}
+
+	return "unknown"
+}
+
+// Server consumes the requests of a queue and replies to them with a Result.
+type Server struct {
+	url       string
+	tlsConfig *tls.Config
+	queue     string
+	consumer  string
+	handler   HandlerFunc
+
+	eventsExchange string
+	eventType      string
+	audience       AudienceFunc
+
+	// zero when the failed requests are not retried
+	maxAttempts int64
+	retryDelay  time.Duration
+
+	state int32
+}
+
+func NewServer(url string, queue string, consumer string, handler HandlerFunc) *Server {
+	return &Server{
+		url:      url,
+		queue:    queue,
+		consumer: consumer,
+		handler:  handler,
+	}
+}
+
+// TLS makes the server connect to an amqps url with the configuration, the default one when it is nil.
+func (s *Server) TLS(config *tls.Config) *Server {
+	s.tlsConfig = config
+	return s
+}
+
+// PublishEvents makes the server publish every Result to the fanout exchange as well,
+// using eventType as the message type.
+func (s *Server) PublishEvents(exchange string, eventType string) *Server {
+	s.eventsExchange = exchange
+	s.eventType = eventType
+	return s
+}
+
+// AudienceFunc returns the users allowed to see the result of a request, along with the user who made it.
+type AudienceFunc func(req *Request, result interface{}) []string
+
+// Audience makes the server send the users allowed to see each event it publishes in its AudienceHeader.
+// Without it, only the user who made the request is.
+func (s *Server) Audience(audience AudienceFunc) *Server {
+	s.audience = audience
+	return s
+}
+
+// audienceOf returns the AudienceHeader of the event publishing the result of the request.
+func (s *Server) audienceOf(req *Request, result interface{}, failed bool) string {
+	users := []string{}
+	seen := make(map[string]bool)
+
+	candidates := []string{req.UserId()}
+	if s.audience != nil && !failed {
+		candidates = append(candidates, s.audience(req, result)...)
+	}
+
+	for _, user := range candidates {
+		if user != "" && !seen[user] {
+			seen[user] = true
+			users = append(users, user)
+		}
+	}
+
+	return strings.Join(users, ",")
+}
+
+// Retry makes the server retry the requests failing with a transient error, waiting delay between
+// two of the maxAttempts attempts. A request failing every attempt is moved to the dead-letter queue
+// before the error is replied. The queue is declared as a command queue, see Client.SendCommand.
+func (s *Server) Retry(maxAttempts int, delay time.Duration) *Server {
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	s.maxAttempts = int64(maxAttempts)
+	s.retryDelay = delay
+	return s
+}
+
+func (s *Server) State() State {
+	return State(atomic.LoadInt32(&s.state))
+}
+
+func (s *Server) setState(st State) {
+	atomic.StoreInt32(&s.state, int32(st))
+}
+
+// HealthHandler answers 200 while the server is consuming its queue and 503 otherwise.
+func (s *Server) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	st := s.State()
+
+	if st != StateConnected {
+		http.Error(w, fmt.Sprintf("Not consuming %s: %s", s.queue, st), http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintf(w, "We're good to go.")
+}
+
+// Health is HealthHandler for a service running several servers, it answers 503 unless all of them are consuming.
+func Health(servers ...*Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, s := range servers {
+			if st := s.State(); st != StateConnected {
+				http.Error(w, fmt.Sprintf("Not consuming %s: %s", s.queue, st), http.StatusServiceUnavailable)
+				return
+			}
+		}
+
+		fmt.Fprintf(w, "We're good to go.")
+	}
+}
+
+// ListenAndServe consumes the queue forever. Whenever the connection or the channel to the
+// broker is lost it reconnects with an exponential backoff, redeclares the queue and resumes consuming.
+func (s *Server) ListenAndServe() {
+	reconnect(s.queue, s.consume, s.setState)
+}
+
+// reconnect calls consume forever, waiting with an exponential backoff between two connections.
+// consume calls connected once it is consuming, which resets the backoff.
+func reconnect(name string, consume func(connected func()) error, setState func(State)) {
+	delay := MinReconnectDelay
+
+	for {
+		setState(StateConnecting)
+
+		err := consume(func() {
+			setState(StateConnected)
+			delay = MinReconnectDelay
+		})
+
+		setState(StateDisconnected)
+		log.Printf("Stopped consuming %s: %s, reconnecting in %s", name, err, delay)
+
+		time.Sleep(delay)
+
+		delay *= 2
+		if delay > MaxReconnectDelay {
+			delay = MaxReconnectDelay
+		}
+	}
+}
+
+// consume serves the queue over a new connection until it is closed. connected is called
+// once the consumer is registered.
+func (s *Server) consume(connected func()) error {
+	conn, err := DialTLS(s.url, s.tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	closed := conn.conn.NotifyClose(make(chan *amqp.Error, 1))
+	channelClosed := conn.channel.NotifyClose(make(chan *amqp.Error, 1))
+
+	var q amqp.Queue
+	if s.maxAttempts > 0 {
+		q, err = conn.declareCommandQueues(s.queue, s.retryDelay)
+	} else {
+		q, err = conn.declareQueue(s.queue)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if s.eventsExchange != "" {
+		err = conn.declareExchange(s.eventsExchange)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = conn.channel.Qos(
+		1,     // prefetch count
+		0,     // prefetch size
+		false, // global
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to set QoS: %w", err)
+	}
+
+	msgs, err := conn.channel.Consume(
+		q.Name,     // queue
+		s.consumer, // consumer
+		false,      // auto-ack
+		false,      // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
+	)
+
+	if err != nil {
+		return fmt.Errorf("failed to register a consumer: %w", err)
+	}
+
+	connected()
+
+	log.Printf("Listening for messages on %s...", q.Name)
+
+	for {
+		select {
+		case d, ok := <-msgs:
+			if !ok {
+				return errors.New("the consumer was closed by the message broker")
+			}
+
+			s.serve(conn.channel, d)
+		case amqpErr := <-closed:
+			return fmt.Errorf("connection closed: %v", amqpErr)
+		case amqpErr := <-channelClosed:
+			return fmt.Errorf("channel closed: %v", amqpErr)
+		}
+	}
+}
+
+func (s *Server) serve(ch *amqp.Channel, d amqp.Delivery) {
+	log.Printf("Message %s received \n", d.CorrelationId)
+
+	var result Result
+
+	req := &Request{d}
+
+	data, err := s.handler(req)
+	if err != nil {
+		log.Println("Failed to handle the request:", err)
+
+		if s.maxAttempts > 0 && !IsPermanent(err) && !s.giveUp(ch, d, err) {
+			// the broker moves it to the retry queue, the command stays pending meanwhile
+			d.Nack(false, false)
+			return
+		}
+
+		result.Err = AsError(err)
+	} else if data != nil {
+		body, err := json.Marshal(data)
+		if err != nil {
+			log.Println("Failed to parse the result to JSON:", err)
+			result.Err = AsError(err)
+		} else {
+			result.Result = string(body)
+		}
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		log.Println("Failed to parse the reply to JSON:", err)
+		d.Nack(false, false)
+		return
+	}
+
+	if d.ReplyTo != "" {
+		err = ch.Publish(
+			"",        // exchange
+			d.ReplyTo, // routing key
+			false,     // mandatory
+			false,     // immediate
+			amqp.Publishing{
+				ContentType:   "text/plain",
+				DeliveryMode:  d.DeliveryMode,
+				CorrelationId: d.CorrelationId,
+				Body:          response,
+			})
+
+		if err != nil {
+			log.Println("Failed to publish the response message:", err)
+			d.Nack(false, false)
+			return
+		}
+	}
+
+	if s.eventsExchange != "" {
+		err = ch.Publish(
+			s.eventsExchange, // exchange
+			"",               // routing key
+			false,            // mandatory
+			false,            // immediate
+			amqp.Publishing{
+				Headers:       amqp.Table{AudienceHeader: s.audienceOf(req, data, result.Err != nil), TenantHeader: req.TenantId()},
+				ContentType:   "text/plain",
+				Type:          s.eventType,
+				CorrelationId: d.CorrelationId,
+				Body:          response,
+			})
+
+		if err != nil {
+			log.Println("Failed to publish the todo event:", err)
+		}
+	}
+
+	d.Ack(false)
+}
+
+// giveUp tells whether the failed request used all its attempts, in which case it is moved to
+// the dead-letter queue. It keeps retrying as long as the request can't be dead-lettered.
+func (s *Server) giveUp(ch *amqp.Channel, d amqp.Delivery, cause error) bool {
+	attempt := attempts(d, s.queue) + 1
+	if attempt < s.maxAttempts {
+		log.Printf("Message %s failed attempt %d of %d, retrying in %s", d.CorrelationId, attempt, s.maxAttempts, s.retryDelay)
+		return false
+	}
+
+	err := deadLetter(ch, d, s.queue, attempt, cause)
+	if err != nil {
+		log.Println("Failed to dead-letter the message:", err)
+		return false
+	}
+
+	log.Printf("Message %s failed %d attempts, moved to %s", d.CorrelationId, attempt, DeadLetterQueue(s.queue))
+	return true
+}
@@ -0,0 +1,47 @@
+// Package mongodb connects the services to the MongoDB deployment.
+package mongodb
+
+import (
+	"context"
+
+	"todo-go/pkg/config"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Mongo error code of a write violating a unique index.
+const duplicateKeyCode = 11000
+
+// Connect connects to the MongoDB deployment of the settings.
+func Connect(db config.Database) (*mongo.Client, error) {
+	ctx := context.Background()
+
+	opts := options.Client().ApplyURI(db.URI()).SetConnectTimeout(db.ConnectTimeout)
+	if db.TLS.Enabled {
+		opts.SetTLSConfig(db.TLS.Config())
+	}
+
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, client.Ping(ctx, nil)
+}
+
+// IsDuplicateKey tells whether the write violated a unique index, findAndModify reports it as a command error.
+func IsDuplicateKey(err error) bool {
+	switch e := err.(type) {
+	case mongo.CommandError:
+		return e.Code == duplicateKeyCode
+	case mongo.WriteException:
+		for _, writeErr := range e.WriteErrors {
+			if writeErr.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+// Package problem writes the errors of the API as RFC 7807 problem details.
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"todo-go/pkg/messaging"
+)
+
+const ContentType = "application/problem+json"
+
+// InternalDetail is the detail of the internal errors. Their messages may name the hosts, databases or
+// indexes of the deployment, they are only logged.
+const InternalDetail = "The request failed, retry later."
+
+// Problem is the body of every error response. The member names are the ones defined by RFC 7807,
+// Code and Details carry the error envelope replied by the DAO.
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Code     string            `json:"code,omitempty"`
+	Details  map[string]string `json:"details,omitempty"`
+}
+
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Status maps the code of a DAO error to an HTTP status.
+func Status(code string) int {
+	switch code {
+	case messaging.CodeInvalid:
+		return http.StatusUnprocessableEntity
+	case messaging.CodeNotFound:
+		return http.StatusNotFound
+	case messaging.CodeConflict, messaging.CodeQuotaExceeded:
+		// a user over their quota isn't forbidden to create todos, it conflicts with the ones they have
+		return http.StatusConflict
+	case messaging.CodeForbidden:
+		return http.StatusForbidden
+	case messaging.CodePreconditionFailed:
+		return http.StatusPreconditionFailed
+	}
+
+	return http.StatusInternalServerError
+}
+
+// FromError builds the problem answering err: the DAO errors get the status of their code,
+// a timeout waiting for the DAO is a 504 and anything else a 500 with InternalDetail.
+func FromError(err error) *Problem {
+	var daoErr *messaging.Error
+	if errors.As(err, &daoErr) {
+		detail := daoErr.Message
+		if daoErr.Code == messaging.CodeInternal {
+			log.Println("Internal error replied by the DAO:", daoErr.Message)
+			detail = InternalDetail
+		}
+
+		p := New(Status(daoErr.Code), detail)
+		p.Code = daoErr.Code
+		p.Details = daoErr.Details
+		return p
+	}
+
+	var timeoutErr *messaging.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		p := New(http.StatusGatewayTimeout, err.Error())
+		p.Details = map[string]string{"correlationId": timeoutErr.CorrelationId}
+		return p
+	}
+
+	log.Println("Internal error:", err)
+	return New(http.StatusInternalServerError, InternalDetail)
+}
+
+// Write sends the problem as the response to r.
+func (p *Problem) Write(w http.ResponseWriter, r *http.Request) {
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		log.Println("Failed to parse the problem to JSON:", err)
+		http.Error(w, p.Detail, p.Status)
+		return
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	w.Write(data)
+}
+
+// Internal logs err and replies to r with a 500 problem, without the message of the error.
+func Internal(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("Failed to handle %s %s: %s", r.Method, r.URL.Path, err)
+	New(http.StatusInternalServerError, InternalDetail).Write(w, r)
+}
+
+// Error replies to r with a problem, like http.Error does with plain text.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	New(status, detail).Write(w, r)
+}
@@ -0,0 +1,66 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"todo-go/pkg/messaging"
+)
+
+func TestStatus(t *testing.T) {
+	tests := map[string]int{
+		messaging.CodeInvalid:            http.StatusUnprocessableEntity,
+		messaging.CodeNotFound:           http.StatusNotFound,
+		messaging.CodeConflict:           http.StatusConflict,
+		messaging.CodeQuotaExceeded:      http.StatusConflict,
+		messaging.CodeForbidden:          http.StatusForbidden,
+		messaging.CodePreconditionFailed: http.StatusPreconditionFailed,
+		messaging.CodeInternal:           http.StatusInternalServerError,
+		"unknown":                        http.StatusInternalServerError,
+	}
+
+	for code, want := range tests {
+		if got := Status(code); got != want {
+			t.Errorf("Status(%s) = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantDetail string
+		wantCode   string
+	}{
+		{"not found", messaging.NotFound("todo 42 not found"), http.StatusNotFound, "todo 42 not found", messaging.CodeNotFound},
+		{"wrapped invalid", fmt.Errorf("patch: %w", messaging.Invalid("bad field")), http.StatusUnprocessableEntity, "bad field", messaging.CodeInvalid},
+		{"internal replied by the DAO", messaging.NewError(messaging.CodeInternal, "E11000 duplicate key error collection: todoDB.todos index: ownerid_1"), http.StatusInternalServerError, InternalDetail, messaging.CodeInternal},
+		{"broker error", errors.New("dial tcp rabbitmq:5672: connection refused"), http.StatusInternalServerError, InternalDetail, ""},
+		{"timeout", &messaging.TimeoutError{CorrelationId: "abc"}, http.StatusGatewayTimeout, "request abc: timed out waiting for the reply", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := FromError(tt.err)
+			if p.Status != tt.wantStatus || p.Detail != tt.wantDetail || p.Code != tt.wantCode {
+				t.Errorf("FromError(%v) = %d %q %q, want %d %q %q", tt.err, p.Status, p.Detail, p.Code, tt.wantStatus, tt.wantDetail, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestAsErrorHidesInternalMessages(t *testing.T) {
+	e := messaging.AsError(errors.New("server selection error: mongo:27017"))
+	if e.Code != messaging.CodeInternal || e.Message != messaging.InternalMessage {
+		t.Errorf("AsError() = %v, want an internal error with %q", e, messaging.InternalMessage)
+	}
+
+	conflict := messaging.Conflict("duplicate")
+	if e := messaging.AsError(fmt.Errorf("insert: %w", conflict)); e != conflict {
+		t.Errorf("AsError() = %v, want the wrapped %v", e, conflict)
+	}
+}
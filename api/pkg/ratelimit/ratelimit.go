@@ -0,0 +1,160 @@
+// Package ratelimit limits the rate of the requests of every client of the API with token buckets.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-go/pkg/auth"
+	"todo-go/pkg/problem"
+	"todo-go/pkg/tenant"
+)
+
+// the buckets idle for longer than that are full again, they are forgotten
+const sweepInterval = time.Minute
+
+// bucket holds the tokens of a client, one is taken by every request and they refill at the rate of the limiter.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter lets every client make rate requests per second, and burst at once. The buckets are kept in the memory
+// of the process: each controller, and each replica of it, limits the requests it handles on its own.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func New(rate float64, burst int) (*Limiter, error) {
+	if rate <= 0 || burst < 1 {
+		return nil, fmt.Errorf("the rate limit must be positive and the burst at least 1, got %g and %d", rate, burst)
+	}
+
+	return &Limiter{
+		rate:      rate,
+		burst:     float64(burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}, nil
+}
+
+// Allow takes a token from the bucket of the client. When it is empty, it returns how long the client has to wait for one.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// sweep forgets the buckets that refilled, a client coming back gets a full one anyway.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Middleware answers a 429 with a Retry-After header to the clients making requests faster than the limiter lets them,
+// but to the public paths, e.g. the health checks. The clients are the authenticated users, see auth.Middleware
+// which has to run first, or the addresses the requests are sent from.
+func (l *Limiter) Middleware(public ...string) func(http.Handler) http.Handler {
+	return l.middleware(ClientKey, public)
+}
+
+// AddressMiddleware is Middleware limiting the addresses the requests are sent from, whoever makes them. It runs
+// before auth.Middleware, so that the requests with invalid tokens are limited as well.
+func (l *Limiter) AddressMiddleware(public ...string) func(http.Handler) http.Handler {
+	return l.middleware(addressKey, public)
+}
+
+func (l *Limiter) middleware(key func(r *http.Request) string, public []string) func(http.Handler) http.Handler {
+	open := make(map[string]bool, len(public))
+	for _, path := range public {
+		open[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if open[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ok, wait := l.Allow(key(r))
+			if !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				problem.Error(w, r, http.StatusTooManyRequests, "Too many requests, retry later.")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ClientKey returns the key of the bucket of the client making the request: its user, or its address.
+func ClientKey(r *http.Request) string {
+	if userId := auth.UserId(r.Context()); userId != "" {
+		return "user:" + tenant.Id(r.Context()) + ":" + userId
+	}
+
+	return addressKey(r)
+}
+
+func addressKey(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// ClientIP returns the address the request is sent from. Behind the proxy, it is the last address of
+// the X-Forwarded-For header, the one the proxy appends: the ones before are set by the client.
+func ClientIP(r *http.Request) string {
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		addrs := strings.Split(values[len(values)-1], ",")
+		if ip := strings.TrimSpace(addrs[len(addrs)-1]); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
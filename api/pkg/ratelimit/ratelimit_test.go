@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"todo-go/pkg/auth"
+	"todo-go/pkg/tenant"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		rate    float64
+		burst   int
+		wantErr bool
+	}{
+		{10, 20, false},
+		{0.5, 1, false},
+		{0, 20, true},
+		{-1, 20, true},
+		{10, 0, true},
+	}
+
+	for _, tt := range tests {
+		if _, err := New(tt.rate, tt.burst); (err != nil) != tt.wantErr {
+			t.Errorf("New(%g, %d) error = %v, want error %v", tt.rate, tt.burst, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAllow(t *testing.T) {
+	l, err := New(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("alice"); !ok {
+			t.Fatalf("request %d of the burst refused", i+1)
+		}
+	}
+
+	ok, wait := l.Allow("alice")
+	if ok || wait <= 0 || wait > 100*time.Millisecond {
+		t.Errorf("Allow() past the burst = %v %v, want a refusal waiting at most 100ms", ok, wait)
+	}
+
+	if ok, _ := l.Allow("bob"); !ok {
+		t.Error("Allow() refused another client")
+	}
+
+	// the bucket refills at the rate of the limiter
+	l.buckets["alice"].last = l.buckets["alice"].last.Add(-time.Second)
+	if ok, _ := l.Allow("alice"); !ok {
+		t.Error("Allow() refused a client whose bucket refilled")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l, err := New(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Allow("alice")
+	l.Allow("bob")
+	l.buckets["alice"].last = time.Now().Add(-time.Hour)
+	l.lastSweep = time.Now().Add(-2 * sweepInterval)
+
+	l.Allow("carol")
+
+	if _, ok := l.buckets["alice"]; ok {
+		t.Error("the refilled bucket of alice isn't forgotten")
+	}
+
+	if _, ok := l.buckets["bob"]; !ok {
+		t.Error("the empty bucket of bob is forgotten")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded []string
+		want      string
+	}{
+		{"remote address", nil, "192.0.2.1"},
+		{"appended by the proxy", []string{"198.51.100.7"}, "198.51.100.7"},
+		{"set by the client first", []string{"203.0.113.9, 198.51.100.7"}, "198.51.100.7"},
+		{"several headers", []string{"203.0.113.9", "198.51.100.7 "}, "198.51.100.7"},
+		{"blank", []string{" "}, "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/todo", nil)
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/todo", nil)
+	if got := ClientKey(r); got != "ip:192.0.2.1" {
+		t.Errorf("ClientKey() = %q, want the address", got)
+	}
+
+	ctx := tenant.WithId(auth.WithUserId(context.Background(), "alice"), "acme")
+	if got := ClientKey(r.WithContext(ctx)); got != "user:acme:alice" {
+		t.Errorf("ClientKey() = %q, want the user in their tenant", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	l, err := New(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	// two users behind the same address share its bucket, the users have one each
+	byAddress := l.AddressMiddleware("/health")(ok)
+	byUser := l.Middleware("/health")(ok)
+
+	tests := []struct {
+		name    string
+		handler http.Handler
+		path    string
+		user    string
+		want    int
+	}{
+		{"first request of the address", byAddress, "/todo", "alice", http.StatusOK},
+		{"second request of the address", byAddress, "/todo", "bob", http.StatusTooManyRequests},
+		{"health check", byAddress, "/health", "", http.StatusOK},
+		{"first request of alice", byUser, "/todo", "alice", http.StatusOK},
+		{"second request of alice", byUser, "/todo", "alice", http.StatusTooManyRequests},
+		{"first request of bob", byUser, "/todo", "bob", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.user != "" {
+				r = r.WithContext(auth.WithUserId(r.Context(), tt.user))
+			}
+
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+
+			if tt.want == http.StatusTooManyRequests && w.Header().Get("Retry-After") != "1" {
+				t.Errorf("Retry-After = %q, want 1", w.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
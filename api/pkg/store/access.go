@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Access is what a user is allowed to do with a todo or a list, each level allowing the ones below.
+type Access int
+
+const (
+	NoAccess Access = iota
+	Viewer          // sees it
+	Editor          // changes the todos
+	Owner           // changes the list and its shares as well
+)
+
+// UserOf returns the user a request is sent on behalf of, the todos and lists belong to users.
+func UserOf(req *messaging.Request) (string, error) {
+	userId := req.UserId()
+	if userId == "" {
+		return "", messaging.Forbidden("the request isn't made by a user")
+	}
+
+	return userId, nil
+}
+
+// ListAccess returns the access of the user to the list: its owner's, or the role it is shared with them as.
+func ListAccess(l *todo.List, userId string) Access {
+	if l.OwnerId == userId {
+		return Owner
+	}
+
+	for _, share := range l.Shares {
+		if share.UserId != userId {
+			continue
+		}
+
+		if share.Role == todo.RoleEditor {
+			return Editor
+		}
+
+		return Viewer
+	}
+
+	return NoAccess
+}
+
+// TodoAccess returns the access of the user to the todo: its owner's, or their access to its list,
+// the owner of the list owning its todos as well.
+func TodoAccess(ctx context.Context, t *todo.Todo, userId string) (Access, error) {
+	if t.OwnerId == userId {
+		return Owner, nil
+	}
+
+	if t.ListId == "" {
+		return NoAccess, nil
+	}
+
+	var l todo.List
+
+	err := Lists(ctx).FindOne(ctx, bson.M{todo.FieldId: t.ListId}).Decode(&l)
+	if err == mongo.ErrNoDocuments {
+		return NoAccess, nil
+	} else if err != nil {
+		return NoAccess, err
+	}
+
+	return ListAccess(&l, userId), nil
+}
+
+// AuthorizeTodo fails unless the user can change the todo, in the trash or not. A todo the user
+// can't see is not found, so that its existence isn't told to them.
+func AuthorizeTodo(ctx context.Context, userId string, todoId string) error {
+	var t todo.Todo
+
+	err := Todos(ctx).FindOne(ctx, bson.M{todo.FieldId: todoId}).Decode(&t)
+	if err == mongo.ErrNoDocuments {
+		return messaging.NotFound("todo %s not found", todoId).WithDetail("id", todoId)
+	} else if err != nil {
+		return err
+	}
+
+	return Authorize(ctx, &t, userId)
+}
+
+// Authorize fails unless the user can change the todo.
+func Authorize(ctx context.Context, t *todo.Todo, userId string) error {
+	a, err := TodoAccess(ctx, t, userId)
+	if err != nil {
+		return err
+	}
+
+	switch a {
+	case NoAccess:
+		return messaging.NotFound("todo %s not found", t.Id).WithDetail("id", t.Id)
+	case Viewer:
+		return messaging.Forbidden("todo %s is shared with you as a viewer", t.Id).WithDetail("id", t.Id)
+	}
+
+	return nil
+}
+
+// AuthorizeList fails unless the user has the needed access to the list, a list the user
+// can't see is not found.
+func AuthorizeList(ctx context.Context, userId string, listId string, needed Access) error {
+	var l todo.List
+
+	err := Lists(ctx).FindOne(ctx, bson.M{todo.FieldId: listId}).Decode(&l)
+	if err == mongo.ErrNoDocuments {
+		return messaging.NotFound("list %s not found", listId).WithDetail("listId", listId)
+	} else if err != nil {
+		return err
+	}
+
+	a := ListAccess(&l, userId)
+
+	if a == NoAccess {
+		return messaging.NotFound("list %s not found", listId).WithDetail("listId", listId)
+	}
+
+	if a < needed {
+		if needed == Owner {
+			return messaging.Forbidden("list %s can only be changed by its owner", listId).WithDetail("listId", listId)
+		}
+
+		return messaging.Forbidden("list %s is shared with you as a viewer", listId).WithDetail("listId", listId)
+	}
+
+	return nil
+}
+
+// CheckShares fails when the list is shared with its owner, who has every access already.
+func CheckShares(ownerId string, shares []todo.Share) error {
+	for _, share := range shares {
+		if share.UserId == ownerId {
+			return messaging.Invalid("a list can't be shared with its owner").WithDetail("userId", ownerId)
+		}
+	}
+
+	return nil
+}
+
+// Audience returns the users allowed to see an event about the todo or the list, see messaging.Server.Audience.
+func (t *Tenants) Audience(req *messaging.Request, result interface{}) []string {
+	switch r := result.(type) {
+	case *todo.Todo:
+		users := []string{r.OwnerId}
+
+		ctx, err := t.Of(req)
+		if err == nil && r.ListId != "" {
+			var l todo.List
+
+			err := Lists(ctx).FindOne(ctx, bson.M{todo.FieldId: r.ListId}).Decode(&l)
+			if err == nil {
+				users = append(users, ListAudience(&l)...)
+			}
+		}
+
+		return users
+	case *todo.List:
+		return ListAudience(r)
+	}
+
+	return nil
+}
+
+// ListAudience returns the users seeing the list: its owner and the users it is shared with.
+func ListAudience(l *todo.List) []string {
+	users := []string{l.OwnerId}
+	for _, share := range l.Shares {
+		users = append(users, share.UserId)
+	}
+
+	return users
+}
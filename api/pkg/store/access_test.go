@@ -0,0 +1,41 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"todo-go/pkg/todo"
+)
+
+func TestListAccess(t *testing.T) {
+	l := &todo.List{OwnerId: "alice", Shares: []todo.Share{
+		{UserId: "bob", Role: todo.RoleEditor},
+		{UserId: "carol", Role: todo.RoleViewer},
+	}}
+
+	for userId, want := range map[string]Access{"alice": Owner, "bob": Editor, "carol": Viewer, "mallory": NoAccess} {
+		if got := ListAccess(l, userId); got != want {
+			t.Errorf("ListAccess(%s) = %d, want %d", userId, got, want)
+		}
+	}
+}
+
+func TestCheckShares(t *testing.T) {
+	shares := []todo.Share{{UserId: "bob", Role: todo.RoleViewer}}
+
+	if err := CheckShares("alice", shares); err != nil {
+		t.Errorf("CheckShares() = %v, want no error", err)
+	}
+
+	if err := CheckShares("bob", shares); err == nil {
+		t.Error("CheckShares() = nil, want an error for a list shared with its owner")
+	}
+}
+
+func TestListAudience(t *testing.T) {
+	l := &todo.List{OwnerId: "alice", Shares: []todo.Share{{UserId: "bob"}, {UserId: "carol"}}}
+
+	if got, want := ListAudience(l), []string{"alice", "bob", "carol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListAudience() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Change is the value of a field of a todo before and after a command, missing while the field isn't set.
+type Change struct {
+	Before interface{} `bson:",omitempty" json:",omitempty"`
+	After  interface{} `bson:",omitempty" json:",omitempty"`
+}
+
+// HistoryEntry is a change of a todo as recorded in the history collection by the DAO handling the command.
+type HistoryEntry struct {
+	TodoId        string
+	Operation     string
+	Changes       map[string]Change
+	Version       int64  `bson:",omitempty" json:",omitempty"` // version of the todo after the change, none once it is deleted
+	Actor         string `bson:",omitempty" json:",omitempty"`
+	CorrelationId string `bson:",omitempty" json:",omitempty"`
+	At            time.Time
+}
+
+// the fields changed by every command, or never shown to the clients, aren't recorded
+var unaudited = map[string]bool{todo.FieldVersion: true, todo.FieldUpdatedAt: true, todo.FieldIdempotencyKey: true}
+
+// Audit records the changes of a command in the history of the todos.
+type Audit struct {
+	Operation     string
+	Actor         string
+	CorrelationId string
+}
+
+// AuditOf returns the audit of the command of the request, made by its user.
+func AuditOf(req *messaging.Request) Audit {
+	return Audit{Operation: req.Operation(), Actor: req.UserId(), CorrelationId: req.CorrelationId}
+}
+
+// createHistoryIndex creates the history collection of a tenant along with the index its listing uses,
+// a collection can't be created by the transactions writing to it.
+func createHistoryIndex(db *DB) error {
+	_, err := db.History.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: "todoid", Value: 1}, {Key: "at", Value: -1}},
+	})
+
+	return err
+}
+
+// Run runs the command in a transaction, appending to the history of every todo matching the filter
+// the changes the command made to it. A failed command changes neither the todos nor their history.
+func (a Audit) Run(ctx context.Context, filter bson.M, command func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	session, err := dbOf(ctx).client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+
+	defer session.EndSession(ctx)
+
+	return session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		before, err := findAudited(sc, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := command(sc)
+		if err != nil {
+			return nil, err
+		}
+
+		after, err := findAudited(sc, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := a.entries(before, after)
+		if len(entries) > 0 {
+			_, err = History(ctx).InsertMany(sc, entries)
+		}
+
+		return result, err
+	})
+}
+
+// findAudited returns the todos matching the filter by id, as stored.
+func findAudited(ctx context.Context, filter bson.M) (map[string]bson.M, error) {
+	cur, err := Todos(ctx).Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 0}))
+	if err != nil {
+		return nil, err
+	}
+
+	defer cur.Close(ctx)
+
+	found := make(map[string]bson.M)
+
+	for cur.Next(ctx) {
+		var doc bson.M
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		id, _ := doc[todo.FieldId].(string)
+		found[id] = doc
+	}
+
+	return found, cur.Err()
+}
+
+// entries returns an entry for every todo the command changed.
+func (a Audit) entries(before map[string]bson.M, after map[string]bson.M) []interface{} {
+	now := todo.Now()
+	entries := []interface{}{}
+
+	ids := make(map[string]bool)
+	for id := range before {
+		ids[id] = true
+	}
+
+	for id := range after {
+		ids[id] = true
+	}
+
+	for id := range ids {
+		changes := make(map[string]Change)
+
+		for field := range keys(before[id], after[id]) {
+			if unaudited[field] || reflect.DeepEqual(before[id][field], after[id][field]) {
+				continue
+			}
+
+			changes[field] = Change{Before: before[id][field], After: after[id][field]}
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+
+		version, _ := after[id][todo.FieldVersion].(int64)
+
+		entries = append(entries, HistoryEntry{
+			TodoId:        id,
+			Operation:     a.Operation,
+			Changes:       changes,
+			Version:       version,
+			Actor:         a.Actor,
+			CorrelationId: a.CorrelationId,
+			At:            now,
+		})
+	}
+
+	return entries
+}
+
+// keys returns the keys of all the documents.
+func keys(docs ...bson.M) map[string]bool {
+	found := make(map[string]bool)
+
+	for _, doc := range docs {
+		for key := range doc {
+			found[key] = true
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestEntries(t *testing.T) {
+	a := Audit{Operation: "update", Actor: "alice", CorrelationId: "c1"}
+
+	before := map[string]bson.M{
+		"1": {todo.FieldId: "1", todo.FieldText: "buy milk", todo.FieldNotes: "2l", todo.FieldVersion: int64(1)},
+		"2": {todo.FieldId: "2", todo.FieldText: "walk", todo.FieldVersion: int64(4)},
+		"3": {todo.FieldId: "3", todo.FieldText: "gone", todo.FieldVersion: int64(2)},
+	}
+
+	after := map[string]bson.M{
+		"1": {todo.FieldId: "1", todo.FieldText: "buy bread", todo.FieldVersion: int64(2)},
+		// only the version and the update time change, nothing is recorded
+		"2": {todo.FieldId: "2", todo.FieldText: "walk", todo.FieldVersion: int64(5), todo.FieldUpdatedAt: "now"},
+		"4": {todo.FieldId: "4", todo.FieldText: "new", todo.FieldVersion: int64(1), todo.FieldIdempotencyKey: "k"},
+	}
+
+	want := map[string]HistoryEntry{
+		"1": {TodoId: "1", Version: 2, Changes: map[string]Change{
+			todo.FieldText:  {Before: "buy milk", After: "buy bread"},
+			todo.FieldNotes: {Before: "2l"},
+		}},
+		"3": {TodoId: "3", Changes: map[string]Change{
+			todo.FieldId:   {Before: "3"},
+			todo.FieldText: {Before: "gone"},
+		}},
+		"4": {TodoId: "4", Version: 1, Changes: map[string]Change{
+			todo.FieldId:   {After: "4"},
+			todo.FieldText: {After: "new"},
+		}},
+	}
+
+	entries := a.entries(before, after)
+	if len(entries) != len(want) {
+		t.Fatalf("entries() = %v, want %d entries", entries, len(want))
+	}
+
+	for _, e := range entries {
+		entry := e.(HistoryEntry)
+
+		if entry.Operation != a.Operation || entry.Actor != a.Actor || entry.CorrelationId != a.CorrelationId || entry.At.IsZero() {
+			t.Errorf("entry %s = %+v, want the operation, actor and correlation id of the audit", entry.TodoId, entry)
+		}
+
+		w := want[entry.TodoId]
+		if entry.Version != w.Version || !reflect.DeepEqual(entry.Changes, w.Changes) {
+			t.Errorf("entry %s = version %d changes %v, want %d %v", entry.TodoId, entry.Version, entry.Changes, w.Version, w.Changes)
+		}
+	}
+}
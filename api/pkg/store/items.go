@@ -0,0 +1,32 @@
+package store
+
+import (
+	"time"
+
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Completion is the end of the update pipelines changing the items of a todo: a todo with items
+// is done when all of them are, and it keeps the time it was first completed at.
+func Completion(now time.Time) bson.A {
+	items := "$" + todo.FieldItems
+
+	return bson.A{
+		bson.M{"$set": bson.M{
+			todo.FieldDone: bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{items, bson.A{}}}}, 0}},
+				bson.M{"$allElementsTrue": bson.A{items + ".done"}},
+				"$" + todo.FieldDone,
+			}},
+		}},
+		bson.M{"$set": bson.M{
+			todo.FieldCompletedAt: bson.M{"$cond": bson.A{
+				"$" + todo.FieldDone,
+				bson.M{"$ifNull": bson.A{"$" + todo.FieldCompletedAt, now}},
+				"$$REMOVE",
+			}},
+		}},
+	}
+}
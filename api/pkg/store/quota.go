@@ -0,0 +1,33 @@
+package store
+
+import (
+	"context"
+	"strconv"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/todo"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// CheckQuota fails when the user owns max todos already, the ones in the trash counting until they are purged.
+// A max that isn't positive lifts the quota. It is checked by every command inserting a todo, in the transaction
+// inserting it: two todos inserted at the same time may both pass it, the quota is there to stop a runaway client
+// rather than to be exact.
+func CheckQuota(ctx context.Context, userId string, max int64) error {
+	if max <= 0 {
+		return nil
+	}
+
+	count, err := Todos(ctx).CountDocuments(ctx, bson.M{todo.FieldOwnerId: userId})
+	if err != nil {
+		return err
+	}
+
+	if count >= max {
+		return messaging.QuotaExceeded("you have %d todos, the most a user can have, the ones in the trash counting until they are purged", count).
+			WithDetail("limit", strconv.FormatInt(max, 10))
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package store holds what the DAOs storing the todos in MongoDB share: the databases of the tenants,
+// the history of the todos, the access of the users to them and their quota.
+package store
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"todo-go/pkg/messaging"
+	"todo-go/pkg/tenant"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Database is the name of the database of the todos of the default tenant, the other tenants have their own,
+// see tenant.Database.
+const Database = "todoDB"
+
+// DB holds the collections of the database of a tenant.
+type DB struct {
+	client  *mongo.Client
+	Todos   *mongo.Collection
+	Lists   *mongo.Collection
+	History *mongo.Collection
+}
+
+// Tenants are the databases of the tenants used so far by a service, their indexes are created the first time they are.
+type Tenants struct {
+	client        *mongo.Client
+	createIndexes func(db *DB) error
+
+	mu  sync.Mutex
+	dbs map[string]*DB
+}
+
+type tenantKey struct{}
+
+// NewTenants returns the databases of the tenants of the deployment, creating the indexes of each with createIndexes.
+func NewTenants(client *mongo.Client, createIndexes func(db *DB) error) *Tenants {
+	return &Tenants{client: client, createIndexes: createIndexes, dbs: make(map[string]*DB)}
+}
+
+// Of returns a context carrying the database of the tenant the request is sent for,
+// the requests sent without a tenant are of the default one.
+func (t *Tenants) Of(req *messaging.Request) (context.Context, error) {
+	id, err := tenant.Of(req.TenantId())
+	if err != nil {
+		return nil, messaging.Invalid("%s", err).WithDetail("tenantId", req.TenantId())
+	}
+
+	return t.With(id)
+}
+
+// With returns a context carrying the database of the tenant, creating its indexes if it is new.
+func (t *Tenants) With(id string) (context.Context, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	db, ok := t.dbs[id]
+	if !ok {
+		d := t.client.Database(tenant.Database(Database, id))
+		db = &DB{client: t.client, Todos: d.Collection("todos"), Lists: d.Collection("lists"), History: d.Collection("history", historyOptions())}
+
+		// a failure is retried by the next request of the tenant
+		err := createHistoryIndex(db)
+		if err != nil {
+			return nil, err
+		}
+
+		err = t.createIndexes(db)
+		if err != nil {
+			return nil, err
+		}
+
+		t.dbs[id] = db
+	}
+
+	return context.WithValue(context.Background(), tenantKey{}, db), nil
+}
+
+// historyOptions decodes the documents of the changes into maps, which are sent back as json objects.
+func historyOptions() *options.CollectionOptions {
+	registry := bson.NewRegistryBuilder().
+		RegisterTypeMapEntry(bsontype.EmbeddedDocument, reflect.TypeOf(bson.M{})).
+		Build()
+
+	return options.Collection().SetRegistry(registry)
+}
+
+func dbOf(ctx context.Context) *DB {
+	return ctx.Value(tenantKey{}).(*DB)
+}
+
+// Todos returns the todos of the tenant of the context, see Tenants.Of.
+func Todos(ctx context.Context) *mongo.Collection {
+	return dbOf(ctx).Todos
+}
+
+// Lists returns the lists of the tenant of the context.
+func Lists(ctx context.Context) *mongo.Collection {
+	return dbOf(ctx).Lists
+}
+
+// History returns the history of the todos of the tenant of the context.
+func History(ctx context.Context) *mongo.Collection {
+	return dbOf(ctx).History
+}
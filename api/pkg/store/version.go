@@ -0,0 +1,27 @@
+package store
+
+import "go.mongodb.org/mongo-driver/bson"
+
+// VersionIn matches the todos at one of the versions of an If-Match, the todos stored before they were versioned are at version 0.
+func VersionIn(versions []int64) bson.M {
+	in := bson.A{}
+	for _, version := range versions {
+		in = append(in, version)
+		if version == 0 {
+			in = append(in, nil)
+		}
+	}
+
+	return bson.M{"$in": in}
+}
+
+// ContainsVersion tells if the version is one of the versions of an If-Match.
+func ContainsVersion(versions []int64, version int64) bool {
+	for _, v := range versions {
+		if v == version {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,26 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestVersionIn(t *testing.T) {
+	// a todo stored before the versions has none and matches version 0
+	want := bson.M{"$in": bson.A{int64(3), int64(0), nil}}
+	if got := VersionIn([]int64{3, 0}); !reflect.DeepEqual(got, want) {
+		t.Errorf("VersionIn() = %v, want %v", got, want)
+	}
+}
+
+func TestContainsVersion(t *testing.T) {
+	if !ContainsVersion([]int64{1, 3}, 3) {
+		t.Error("ContainsVersion() = false, want true")
+	}
+
+	if ContainsVersion([]int64{1, 3}, 2) || ContainsVersion(nil, 0) {
+		t.Error("ContainsVersion() = true, want false")
+	}
+}
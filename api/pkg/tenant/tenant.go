@@ -0,0 +1,161 @@
+// Package tenant resolves the tenant of a request, the teams sharing a deployment each having their own databases.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Default is the tenant of the requests that don't name one, its databases are the ones of a single tenant deployment.
+const Default = "default"
+
+// Header is the HTTP header naming the tenant of a request.
+const Header = "X-Tenant-Id"
+
+// ids are used in the names of the databases, which can't be longer than 64 bytes.
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+var ErrInvalidId = errors.New("invalid tenant id")
+var ErrUnknown = errors.New("unknown tenant")
+
+// Validate fails unless the id can name a tenant.
+func Validate(id string) error {
+	if !idPattern.MatchString(id) {
+		return fmt.Errorf("%w %q: 1 to 32 lower case letters, digits or dashes", ErrInvalidId, id)
+	}
+
+	return nil
+}
+
+// Of returns the tenant of the id sent along with a message, the default one when there is none.
+func Of(id string) (string, error) {
+	if id == "" {
+		return Default, nil
+	}
+
+	return id, Validate(id)
+}
+
+// Database returns the name of the database of the tenant, the base one for the default tenant.
+func Database(base string, id string) string {
+	if id == Default {
+		return base
+	}
+
+	return base + "_" + id
+}
+
+// FromDatabase returns the tenant of a database named by Database, if it is one.
+func FromDatabase(base string, name string) (string, bool) {
+	if name == base {
+		return Default, true
+	}
+
+	id := strings.TrimPrefix(name, base+"_")
+	if id == name || Validate(id) != nil {
+		return "", false
+	}
+
+	return id, true
+}
+
+// Any allows every tenant when it is listed among the allowed ones.
+const Any = "*"
+
+// Resolver resolves the tenant a request names, by its Header or by the subdomain of Domain it is sent to.
+type Resolver struct {
+	Domain  string          // e.g. todo.example.com, the requests to acme.todo.example.com are of the acme tenant
+	Allowed map[string]bool // the tenants that can be named besides the default one
+	Any     bool            // every tenant can be named
+}
+
+// NewResolver returns a resolver of the subdomains of the domain, if any, and of the allowed tenants.
+// Only the default tenant is allowed when none is, and every tenant when Any is.
+func NewResolver(domain string, allowed []string) (*Resolver, error) {
+	r := Resolver{Domain: strings.ToLower(strings.Trim(domain, ".")), Allowed: make(map[string]bool)}
+
+	for _, id := range allowed {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+
+		if id == Any {
+			r.Any = true
+			continue
+		}
+
+		if err := Validate(id); err != nil {
+			return nil, err
+		}
+
+		r.Allowed[id] = true
+	}
+
+	return &r, nil
+}
+
+// Resolve returns the tenant the request names, none when it doesn't name one.
+func (res *Resolver) Resolve(r *http.Request) (string, error) {
+	id := strings.TrimSpace(r.Header.Get(Header))
+	if id == "" {
+		id = res.subdomain(r.Host)
+	}
+
+	if id == "" {
+		return "", nil
+	}
+
+	if err := Validate(id); err != nil {
+		return "", err
+	}
+
+	// a tenant nobody allowed would have its databases created by the requests naming it
+	if !res.Any && !res.Allowed[id] && id != Default {
+		return "", fmt.Errorf("%w %q", ErrUnknown, id)
+	}
+
+	return id, nil
+}
+
+// subdomain returns the label of the host right below the domain, if the host is a subdomain of it.
+func (res *Resolver) subdomain(host string) string {
+	if res.Domain == "" {
+		return ""
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	label := strings.TrimSuffix(host, "."+res.Domain)
+	if label == host || strings.Contains(label, ".") {
+		return ""
+	}
+
+	return label
+}
+
+type idKey struct{}
+
+// WithId returns a copy of the context carrying the tenant of the request.
+func WithId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, idKey{}, id)
+}
+
+// Id returns the tenant of the request, the default one when it isn't set.
+func Id(ctx context.Context) string {
+	id, _ := ctx.Value(idKey{}).(string)
+	if id == "" {
+		return Default
+	}
+
+	return id
+}
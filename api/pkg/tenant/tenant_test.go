@@ -0,0 +1,72 @@
+package tenant
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		host    string
+		header  string
+		want    string
+		wantErr error
+	}{
+		{"nothing named", nil, "todo.example.com", "", "", nil},
+		{"default", nil, "todo.example.com", "default", "default", nil},
+		{"unknown by default", nil, "todo.example.com", "acme", "", ErrUnknown},
+		{"allowed header", []string{"acme", " globex "}, "todo.example.com", "globex", "globex", nil},
+		{"unknown header", []string{"acme"}, "todo.example.com", "initech", "", ErrUnknown},
+		{"any", []string{Any}, "todo.example.com", "initech", "initech", nil},
+		{"invalid", []string{Any}, "todo.example.com", "Acme!", "", ErrInvalidId},
+		{"subdomain", []string{"acme"}, "ACME.todo.example.com:8080", "", "acme", nil},
+		{"unknown subdomain", []string{"acme"}, "initech.todo.example.com", "", "", ErrUnknown},
+		{"header over subdomain", []string{"acme", "globex"}, "acme.todo.example.com", "globex", "globex", nil},
+		{"deeper subdomain", []string{Any}, "a.acme.todo.example.com", "", "", nil},
+		{"other domain", []string{Any}, "acme.example.org", "", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := NewResolver("todo.example.com.", tt.allowed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest("GET", "/todo", nil)
+			r.Host = tt.host
+			if tt.header != "" {
+				r.Header.Set(Header, tt.header)
+			}
+
+			got, err := res.Resolve(r)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("Resolve() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewResolverInvalid(t *testing.T) {
+	if _, err := NewResolver("", []string{"acme", "Acme"}); !errors.Is(err, ErrInvalidId) {
+		t.Errorf("NewResolver() error = %v, want %v", err, ErrInvalidId)
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	for _, id := range []string{Default, "acme"} {
+		got, ok := FromDatabase("todoDB", Database("todoDB", id))
+		if !ok || got != id {
+			t.Errorf("FromDatabase(Database(%s)) = %q, %v", id, got, ok)
+		}
+	}
+
+	for _, name := range []string{"authDB", "todoDB_", "todoDB_Acme", "todoDBacme"} {
+		if id, ok := FromDatabase("todoDB", name); ok {
+			t.Errorf("FromDatabase(%s) = %q, want none", name, id)
+		}
+	}
+}
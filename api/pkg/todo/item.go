@@ -0,0 +1,95 @@
+package todo
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Names of the fields of the checklist items of a todo.
+const (
+	FieldItems    = "items"
+	FieldPosition = "position"
+)
+
+// MaxItems is the number of checklist items a todo can have.
+const MaxItems = 100
+
+// Item is a step of a todo, its checklist is ordered. A todo with items is done when all of them are.
+type Item struct {
+	Id   string
+	Text string
+	Done bool
+}
+
+// Validate checks the fields set by the client.
+func (i *Item) Validate() error {
+	return validateItemText(i.Text)
+}
+
+func validateItemText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return invalidField(FieldText, "the text of an item can't be empty")
+	}
+
+	if len(text) > MaxTextLength {
+		return invalidField(FieldText, "the text of an item can't be longer than %d characters", MaxTextLength)
+	}
+
+	return nil
+}
+
+func validateItems(items []Item) error {
+	if len(items) > MaxItems {
+		return invalidField(FieldItems, "a todo can't have more than %d items", MaxItems)
+	}
+
+	for i := range items {
+		if err := items[i].Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ItemsDone tells whether all the items are done, a todo without items isn't derived from them.
+func ItemsDone(items []Item) bool {
+	for _, item := range items {
+		if !item.Done {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidatePosition checks the position an item is added or moved to, the first one being 0.
+func ValidatePosition(position int) error {
+	if position < 0 || position >= MaxItems {
+		return invalidField(FieldPosition, "the position of an item is between 0 and %d", MaxItems-1)
+	}
+
+	return nil
+}
+
+// itemEditable decodes and validates the value of every field of an item the clients can change.
+var itemEditable = map[string]func(json.RawMessage) (interface{}, error){
+	FieldText: func(raw json.RawMessage) (interface{}, error) {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+
+		return text, validateItemText(text)
+	},
+	FieldDone: func(raw json.RawMessage) (interface{}, error) {
+		var done bool
+		err := json.Unmarshal(raw, &done)
+		return done, err
+	},
+}
+
+// DecodeItemField is DecodeField for the fields of an item.
+func DecodeItemField(field string, raw json.RawMessage) (interface{}, error) {
+	return decodeField(itemEditable, field, raw)
+}
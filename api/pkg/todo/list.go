@@ -0,0 +1,161 @@
+package todo
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// Names of the fields of a list in the collection, the patches use them as well.
+const (
+	FieldName        = "name"
+	FieldDescription = "description"
+	FieldShares      = "shares"
+)
+
+// Names of the fields of a share.
+const (
+	FieldUserId = "userid"
+	FieldRole   = "role"
+)
+
+// Roles of the users a list is shared with: viewers see its todos, editors change them as well.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+)
+
+// Limits of the fields of a list set by the clients.
+const (
+	MaxNameLength        = 100
+	MaxDescriptionLength = 1000
+	MaxShares            = 50
+)
+
+// Share gives a user access to a list and its todos.
+type Share struct {
+	UserId string
+	Role   string
+}
+
+// List is a list of todos, e.g. a project, as stored in the collection and returned to the clients.
+// Its todos refer to it by their ListId. Only its owner changes it and its shares.
+type List struct {
+	Id          string
+	OwnerId     string `json:",omitempty" bson:",omitempty"`
+	Name        string
+	Description string     `json:",omitempty" bson:",omitempty"`
+	Shares      []Share    `json:",omitempty" bson:",omitempty"`
+	CreatedAt   *time.Time `json:",omitempty" bson:",omitempty"`
+	UpdatedAt   *time.Time `json:",omitempty" bson:",omitempty"`
+	Version     int64
+
+	// key of the command that created the list, never sent back to the clients
+	IdempotencyKey string `json:"-" bson:",omitempty"`
+}
+
+// Validate checks the fields set by the client.
+func (l *List) Validate() error {
+	if err := validateName(l.Name); err != nil {
+		return err
+	}
+
+	if err := validateDescription(l.Description); err != nil {
+		return err
+	}
+
+	return validateShares(l.Shares)
+}
+
+// Created sets the fields maintained by the DAOs on a list about to be inserted.
+func (l *List) Created(id string, now time.Time) {
+	l.Id = id
+	l.Version = 1
+	l.CreatedAt = &now
+	l.UpdatedAt = &now
+}
+
+func validateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return invalidField(FieldName, "the name of a list can't be empty")
+	}
+
+	if len(name) > MaxNameLength {
+		return invalidField(FieldName, "the name of a list can't be longer than %d characters", MaxNameLength)
+	}
+
+	return nil
+}
+
+func validateDescription(description string) error {
+	if len(description) > MaxDescriptionLength {
+		return invalidField(FieldDescription, "the description of a list can't be longer than %d characters", MaxDescriptionLength)
+	}
+
+	return nil
+}
+
+func validateShares(shares []Share) error {
+	if len(shares) > MaxShares {
+		return invalidField(FieldShares, "a list can't be shared with more than %d users", MaxShares)
+	}
+
+	users := make(map[string]bool, len(shares))
+
+	for _, share := range shares {
+		if strings.TrimSpace(share.UserId) == "" {
+			return invalidField(FieldShares, "a list is shared with users, the id of the user can't be empty")
+		}
+
+		if share.Role != RoleViewer && share.Role != RoleEditor {
+			return invalidField(FieldShares, "the role of a user must be %s or %s", RoleViewer, RoleEditor)
+		}
+
+		if users[share.UserId] {
+			return invalidField(FieldShares, "the list is shared with user %s twice", share.UserId)
+		}
+
+		users[share.UserId] = true
+	}
+
+	return nil
+}
+
+// listEditable decodes and validates the value of every field of a list the clients can change.
+var listEditable = map[string]func(json.RawMessage) (interface{}, error){
+	FieldName: func(raw json.RawMessage) (interface{}, error) {
+		var name string
+		if err := json.Unmarshal(raw, &name); err != nil {
+			return nil, err
+		}
+
+		return name, validateName(name)
+	},
+	FieldDescription: func(raw json.RawMessage) (interface{}, error) {
+		var description string
+		if err := json.Unmarshal(raw, &description); err != nil {
+			return nil, err
+		}
+
+		return description, validateDescription(description)
+	},
+	FieldShares: func(raw json.RawMessage) (interface{}, error) {
+		var shares []Share
+		if err := json.Unmarshal(raw, &shares); err != nil {
+			return nil, err
+		}
+
+		return shares, validateShares(shares)
+	},
+}
+
+// ListRemovable tells whether the clients can remove the field of a list, a list always has a name.
+func ListRemovable(field string) bool {
+	_, ok := listEditable[field]
+	return ok && field != FieldName
+}
+
+// DecodeListField is DecodeField for the fields of a list.
+func DecodeListField(field string, raw json.RawMessage) (interface{}, error) {
+	return decodeField(listEditable, field, raw)
+}
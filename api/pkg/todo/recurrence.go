@@ -0,0 +1,154 @@
+package todo
+
+import (
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+// Names of the fields of a recurring todo.
+const (
+	FieldRecurrence = "recurrence"
+	FieldSeriesId   = "seriesid"
+)
+
+// MaxRecurrenceLength is the length of the longest recurrence rule of a todo.
+const MaxRecurrenceLength = 500
+
+// maxSkipped bounds the occurrences before the listed ones that Occurrences goes through, see periods.
+const maxSkipped = 10000
+
+// periods are the fixed lengths of the periods of the rules, Occurrences skips them by whole periods.
+// The months and the years vary.
+var periods = map[rrule.Frequency]time.Duration{
+	rrule.HOURLY: time.Hour,
+	rrule.DAILY:  24 * time.Hour,
+	rrule.WEEKLY: 7 * 24 * time.Hour,
+}
+
+// parseRecurrence parses the iCalendar RRULE of a todo, e.g. FREQ=WEEKLY;BYDAY=MO. The rule starts
+// at the due date of the todo, in UTC, and repeats at most hourly.
+func parseRecurrence(rule string) (*rrule.ROption, error) {
+	if len(rule) > MaxRecurrenceLength {
+		return nil, invalidField(FieldRecurrence, "the recurrence of a todo can't be longer than %d characters", MaxRecurrenceLength)
+	}
+
+	option, err := rrule.StrToROption(rule)
+	if err != nil {
+		return nil, invalidField(FieldRecurrence, "invalid recurrence rule %q: %s", rule, err)
+	}
+
+	if !option.Dtstart.IsZero() {
+		return nil, invalidField(FieldRecurrence, "the recurrence of a todo starts at its due date, it has no DTSTART")
+	}
+
+	if option.Freq > rrule.HOURLY {
+		return nil, invalidField(FieldRecurrence, "a todo can't repeat more often than hourly")
+	}
+
+	if _, err := rrule.NewRRule(*option); err != nil {
+		return nil, invalidField(FieldRecurrence, "invalid recurrence rule %q: %s", rule, err)
+	}
+
+	return option, nil
+}
+
+// validateRecurrence returns the rule as it is stored, in upper case and without the RRULE: prefix.
+func validateRecurrence(rule string) (string, error) {
+	option, err := parseRecurrence(strings.ToUpper(strings.TrimSpace(rule)))
+	if err != nil {
+		return "", err
+	}
+
+	return option.RRuleString(), nil
+}
+
+// start is the start of the rule of a todo due at, the rules only keep whole seconds.
+func start(at time.Time) time.Time {
+	return at.UTC().Truncate(time.Second)
+}
+
+// NextOccurrence returns the due date of the occurrence following the one due at, along with the
+// rule it repeats with: a rule with a COUNT has one occurrence less. It returns false once the rule ended.
+func NextOccurrence(rule string, at time.Time) (time.Time, string, bool) {
+	option, err := parseRecurrence(rule)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+
+	option.Dtstart = start(at)
+	r, err := rrule.NewRRule(*option)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+
+	// a todo due off its schedule is followed by the first occurrence, without counting it
+	next := r.After(option.Dtstart, true)
+	if next.Equal(option.Dtstart) {
+		next = r.After(option.Dtstart, false)
+
+		if option.Count > 0 {
+			option.Count--
+		}
+	}
+
+	if next.IsZero() {
+		return time.Time{}, "", false
+	}
+
+	option.Dtstart = time.Time{}
+	return next.Truncate(time.Millisecond), option.RRuleString(), true
+}
+
+// Occurrences returns the due dates between from, included, and to, excluded, of the occurrences
+// following the one due at, at most max of them.
+func Occurrences(rule string, at time.Time, from time.Time, to time.Time, max int) []time.Time {
+	option, err := parseRecurrence(rule)
+	if err != nil {
+		return nil
+	}
+
+	first := start(at)
+	option.Dtstart = skip(option, first, from)
+
+	r, err := rrule.NewRRule(*option)
+	if err != nil {
+		return nil
+	}
+
+	occurrences := []time.Time{}
+	next := r.Iterator()
+
+	for skipped := 0; len(occurrences) < max && skipped < maxSkipped; {
+		occurrence, ok := next()
+		if !ok || !occurrence.Before(to) {
+			break
+		}
+
+		if !occurrence.After(first) || occurrence.Before(from) {
+			skipped++
+			continue
+		}
+
+		occurrences = append(occurrences, occurrence.Truncate(time.Millisecond))
+	}
+
+	return occurrences
+}
+
+// skip returns the start of the rule the occurrences from from on are listed from: the last start of
+// a period before from, which keeps the schedule of the rule. The rules with a COUNT or without fixed
+// periods start at first, their occurrences before from count.
+func skip(option *rrule.ROption, first time.Time, from time.Time) time.Time {
+	period, ok := periods[option.Freq]
+	if !ok || option.Count > 0 || !from.After(first) {
+		return first
+	}
+
+	if option.Interval > 1 {
+		period *= time.Duration(option.Interval)
+	}
+
+	return first.Add(from.Sub(first) / period * period)
+}
@@ -0,0 +1,194 @@
+package todo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+func TestValidateRecurrence(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    string
+		wantErr bool
+	}{
+		{"freq=weekly;byday=mo", "FREQ=WEEKLY;BYDAY=MO", false},
+		{" RRULE:FREQ=DAILY;COUNT=3 ", "FREQ=DAILY;COUNT=3", false},
+		{"FREQ=HOURLY;INTERVAL=2", "FREQ=HOURLY;INTERVAL=2", false},
+		{"FREQ=MINUTELY", "", true},
+		{"FREQ=DAILY;DTSTART=20240301T090000Z", "", true},
+		{"FREQ=SOMETIMES", "", true},
+		{"", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := validateRecurrence(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateRecurrence(%q) error = %v, want error %v", tt.rule, err, tt.wantErr)
+			}
+
+			if err == nil && got != tt.want {
+				t.Errorf("validateRecurrence(%q) = %q, want %q", tt.rule, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	day := func(d int, hour int, nsec int) time.Time {
+		return time.Date(2024, 3, d, hour, 0, 0, nsec, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		rule     string
+		at       time.Time
+		want     time.Time
+		wantRule string
+		wantOk   bool
+	}{
+		{"daily", "FREQ=DAILY", day(1, 9, 0), day(2, 9, 0), "FREQ=DAILY", true},
+		{"count", "FREQ=DAILY;COUNT=3", day(1, 9, 0), day(2, 9, 0), "FREQ=DAILY;COUNT=2", true},
+		{"count with milliseconds", "FREQ=DAILY;COUNT=3", day(1, 9, 123000000), day(2, 9, 0), "FREQ=DAILY;COUNT=2", true},
+		{"last of the count", "FREQ=DAILY;COUNT=1", day(1, 9, 0), time.Time{}, "", false},
+		{"last of the count with milliseconds", "FREQ=DAILY;COUNT=1", day(1, 9, 500000000), time.Time{}, "", false},
+		// 2024-03-06 is a Wednesday, the next Monday is the first occurrence
+		{"off schedule", "FREQ=WEEKLY;BYDAY=MO;COUNT=2", day(6, 9, 0), day(11, 9, 0), "FREQ=WEEKLY;COUNT=2;BYDAY=MO", true},
+		{"until", "FREQ=DAILY;UNTIL=20240302T000000Z", day(1, 9, 0), time.Time{}, "", false},
+		{"invalid", "FREQ=SOMETIMES", day(1, 9, 0), time.Time{}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rule, ok := NextOccurrence(tt.rule, tt.at)
+			if ok != tt.wantOk || !got.Equal(tt.want) || rule != tt.wantRule {
+				t.Errorf("NextOccurrence() = %v %q %v, want %v %q %v", got, rule, ok, tt.want, tt.wantRule, tt.wantOk)
+			}
+		})
+	}
+}
+
+// TestNextOccurrenceCount completes every occurrence of a series due off the second, it has COUNT of them.
+func TestNextOccurrenceCount(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 0, 0, 987000000, time.UTC)
+	rule := "FREQ=HOURLY;COUNT=4"
+
+	occurrences := 1
+	for {
+		next, nextRule, ok := NextOccurrence(rule, at)
+		if !ok {
+			break
+		}
+
+		occurrences++
+		if occurrences > 10 {
+			t.Fatalf("the series doesn't end, at %v with %q", next, nextRule)
+		}
+
+		at, rule = next, nextRule
+	}
+
+	if occurrences != 4 {
+		t.Errorf("the series has %d occurrences, want 4", occurrences)
+	}
+}
+
+// occurrences lists the occurrences the way Occurrences does, going through all of them from at.
+func occurrences(t *testing.T, rule string, at time.Time, from time.Time, to time.Time, max int) []time.Time {
+	option, err := parseRecurrence(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	option.Dtstart = start(at)
+	r, err := rrule.NewRRule(*option)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := []time.Time{}
+	for _, occurrence := range r.Between(from, to, true) {
+		if len(listed) < max && occurrence.After(start(at)) && occurrence.Before(to) {
+			listed = append(listed, occurrence)
+		}
+	}
+
+	return listed
+}
+
+func TestOccurrences(t *testing.T) {
+	at := time.Date(2021, 1, 13, 9, 30, 15, 250000000, time.UTC)
+	from := time.Date(2024, 3, 6, 17, 45, 0, 0, time.UTC)
+	to := from.AddDate(0, 2, 0)
+
+	rules := []string{
+		"FREQ=HOURLY",
+		"FREQ=HOURLY;INTERVAL=5",
+		"FREQ=HOURLY;INTERVAL=7;BYDAY=MO,TU",
+		"FREQ=DAILY;BYHOUR=9,17",
+		"FREQ=DAILY;INTERVAL=3",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+		"FREQ=WEEKLY;WKST=SU;INTERVAL=3;BYDAY=SU,SA",
+		"FREQ=MONTHLY;BYMONTHDAY=31",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;UNTIL=20240310T000000Z",
+		"FREQ=DAILY;COUNT=1200",
+	}
+
+	for _, rule := range rules {
+		t.Run(rule, func(t *testing.T) {
+			got := Occurrences(rule, at, from, to, 50)
+			want := occurrences(t, rule, at, from, to, 50)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Occurrences() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestOccurrencesFromTheStart(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 0, 0, 500000000, time.UTC)
+
+	got := Occurrences("FREQ=DAILY;COUNT=3", at, at.AddDate(0, 0, -7), at.AddDate(0, 1, 0), 10)
+	want := []time.Time{time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC), time.Date(2024, 3, 3, 9, 0, 0, 0, time.UTC)}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Occurrences() = %v, want %v", got, want)
+	}
+
+	if got := Occurrences("FREQ=DAILY", at, at, at.AddDate(0, 1, 0), 2); len(got) != 2 {
+		t.Errorf("Occurrences() = %v, want 2 of them", got)
+	}
+}
+
+// TestOccurrencesSkip checks an hourly todo due decades ago doesn't go through every hour since.
+func TestOccurrencesSkip(t *testing.T) {
+	option, err := parseRecurrence("FREQ=HOURLY;INTERVAL=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first := time.Date(1990, 1, 1, 10, 20, 30, 0, time.UTC)
+	from := time.Date(2024, 3, 6, 17, 45, 0, 0, time.UTC)
+
+	skipped := skip(option, first, from)
+	if skipped.After(from) || from.Sub(skipped) >= 3*time.Hour || skipped.Sub(first)%(3*time.Hour) != 0 {
+		t.Errorf("skip() = %v, want the last start of a period before %v", skipped, from)
+	}
+
+	got := Occurrences("FREQ=HOURLY;INTERVAL=3", first, from, from.Add(12*time.Hour), 10)
+	want := []time.Time{
+		time.Date(2024, 3, 6, 19, 20, 30, 0, time.UTC),
+		time.Date(2024, 3, 6, 22, 20, 30, 0, time.UTC),
+		time.Date(2024, 3, 7, 1, 20, 30, 0, time.UTC),
+		time.Date(2024, 3, 7, 4, 20, 30, 0, time.UTC),
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Occurrences() = %v, want %v", got, want)
+	}
+}
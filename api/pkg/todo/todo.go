@@ -0,0 +1,305 @@
+// Package todo is the model of the todos and of their lists shared by the controllers, which
+// validate what the clients send, and the DAOs, which store it.
+package todo
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"todo-go/pkg/messaging"
+)
+
+// Names of the fields in the collection, the patches use them as well.
+const (
+	FieldId             = "id"
+	FieldListId         = "listid"
+	FieldOwnerId        = "ownerid"
+	FieldText           = "text"
+	FieldDone           = "done"
+	FieldNotes          = "notes"
+	FieldPriority       = "priority"
+	FieldTags           = "tags"
+	FieldDueAt          = "dueat"
+	FieldCreatedAt      = "createdat"
+	FieldUpdatedAt      = "updatedat"
+	FieldCompletedAt    = "completedat"
+	FieldDeletedAt      = "deletedat"
+	FieldVersion        = "version"
+	FieldIdempotencyKey = "idempotencykey"
+)
+
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+var priorities = map[string]bool{PriorityLow: true, PriorityNormal: true, PriorityHigh: true, PriorityUrgent: true}
+
+// IsPriority tells whether p is one of the priorities of a todo.
+func IsPriority(p string) bool {
+	return priorities[p]
+}
+
+// Limits of the fields set by the clients.
+const (
+	MaxTextLength  = 500
+	MaxNotesLength = 10000
+	MaxTags        = 20
+	MaxTagLength   = 50
+)
+
+// Todo is a todo as stored in the collection and returned to the clients. The timestamps, the owner and
+// the version are maintained by the DAOs. A deleted todo stays in the trash until it is restored or purged.
+type Todo struct {
+	Id          string
+	OwnerId     string `json:",omitempty" bson:",omitempty"` // user who created the todo
+	ListId      string `json:",omitempty" bson:",omitempty"` // list the todo belongs to, if any
+	Text        string
+	Done        bool
+	Notes       string     `json:",omitempty" bson:",omitempty"`
+	Priority    string     `json:",omitempty" bson:",omitempty"`
+	Tags        []string   `json:",omitempty" bson:",omitempty"`
+	DueAt       *time.Time `json:",omitempty" bson:",omitempty"`
+	Items       []Item     `json:",omitempty" bson:",omitempty"`
+	Recurrence  string     `json:",omitempty" bson:",omitempty"` // RRULE the todo repeats with
+	SeriesId    string     `json:",omitempty" bson:",omitempty"` // first todo of the series it was repeated from
+	CreatedAt   *time.Time `json:",omitempty" bson:",omitempty"`
+	UpdatedAt   *time.Time `json:",omitempty" bson:",omitempty"`
+	CompletedAt *time.Time `json:",omitempty" bson:",omitempty"`
+	DeletedAt   *time.Time `json:",omitempty" bson:",omitempty"` // set while the todo is in the trash
+	Version     int64
+
+	// key of the command that created the todo, never sent back to the clients
+	IdempotencyKey string `json:"-" bson:",omitempty"`
+}
+
+// Now is the time stored in the todos, mongo only keeps the milliseconds.
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// Validate checks the fields set by the client, trimming and deduplicating the tags and
+// storing the due date in UTC and the recurrence without its RRULE: prefix.
+func (t *Todo) Validate() error {
+	if err := validateText(t.Text); err != nil {
+		return err
+	}
+
+	if err := validateNotes(t.Notes); err != nil {
+		return err
+	}
+
+	if err := validatePriority(t.Priority); err != nil {
+		return err
+	}
+
+	tags, err := normalizeTags(t.Tags)
+	if err != nil {
+		return err
+	}
+
+	t.Tags = tags
+
+	if err := validateItems(t.Items); err != nil {
+		return err
+	}
+
+	if t.Recurrence != "" {
+		rule, err := validateRecurrence(t.Recurrence)
+		if err != nil {
+			return err
+		}
+
+		t.Recurrence = rule
+	}
+
+	if t.DueAt != nil {
+		dueAt := t.DueAt.UTC().Truncate(time.Millisecond)
+		t.DueAt = &dueAt
+	}
+
+	return nil
+}
+
+// Created sets the fields maintained by the DAOs on a todo about to be inserted, its items
+// already have their ids.
+func (t *Todo) Created(id string, now time.Time) {
+	t.Id = id
+	t.Version = 1
+	t.CreatedAt = &now
+	t.UpdatedAt = &now
+	t.CompletedAt = nil
+
+	if len(t.Items) > 0 {
+		t.Done = ItemsDone(t.Items)
+	}
+
+	if t.Done {
+		t.CompletedAt = &now
+	}
+}
+
+func invalidField(field string, format string, args ...interface{}) error {
+	return messaging.Invalid(format, args...).WithDetail("field", field)
+}
+
+func validateText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return invalidField(FieldText, "the text of a todo can't be empty")
+	}
+
+	if len(text) > MaxTextLength {
+		return invalidField(FieldText, "the text of a todo can't be longer than %d characters", MaxTextLength)
+	}
+
+	return nil
+}
+
+func validateNotes(notes string) error {
+	if len(notes) > MaxNotesLength {
+		return invalidField(FieldNotes, "the notes of a todo can't be longer than %d characters", MaxNotesLength)
+	}
+
+	return nil
+}
+
+func validatePriority(priority string) error {
+	if priority != "" && !priorities[priority] {
+		return invalidField(FieldPriority, "unknown priority %q, it is one of low, normal, high or urgent", priority)
+	}
+
+	return nil
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := make(map[string]bool)
+
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "" || len(tag) > MaxTagLength {
+			return nil, invalidField(FieldTags, "a tag has between 1 and %d characters", MaxTagLength)
+		}
+
+		if !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+
+	if len(normalized) > MaxTags {
+		return nil, invalidField(FieldTags, "a todo can't have more than %d tags", MaxTags)
+	}
+
+	return normalized, nil
+}
+
+// editable decodes and validates the value of every field the clients can change.
+var editable = map[string]func(json.RawMessage) (interface{}, error){
+	FieldListId: func(raw json.RawMessage) (interface{}, error) {
+		var listId string
+		if err := json.Unmarshal(raw, &listId); err != nil {
+			return nil, err
+		}
+
+		if listId == "" {
+			return nil, invalidField(FieldListId, "the list of a todo can't be empty, remove it instead")
+		}
+
+		return listId, nil
+	},
+	FieldText: func(raw json.RawMessage) (interface{}, error) {
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return nil, err
+		}
+
+		return text, validateText(text)
+	},
+	FieldDone: func(raw json.RawMessage) (interface{}, error) {
+		var done bool
+		err := json.Unmarshal(raw, &done)
+		return done, err
+	},
+	FieldNotes: func(raw json.RawMessage) (interface{}, error) {
+		var notes string
+		if err := json.Unmarshal(raw, &notes); err != nil {
+			return nil, err
+		}
+
+		return notes, validateNotes(notes)
+	},
+	FieldPriority: func(raw json.RawMessage) (interface{}, error) {
+		var priority string
+		if err := json.Unmarshal(raw, &priority); err != nil {
+			return nil, err
+		}
+
+		return priority, validatePriority(priority)
+	},
+	FieldTags: func(raw json.RawMessage) (interface{}, error) {
+		var tags []string
+		if err := json.Unmarshal(raw, &tags); err != nil {
+			return nil, err
+		}
+
+		return normalizeTags(tags)
+	},
+	FieldDueAt: func(raw json.RawMessage) (interface{}, error) {
+		var dueAt time.Time
+		if err := json.Unmarshal(raw, &dueAt); err != nil {
+			return nil, err
+		}
+
+		return dueAt.UTC().Truncate(time.Millisecond), nil
+	},
+	FieldRecurrence: func(raw json.RawMessage) (interface{}, error) {
+		var rule string
+		if err := json.Unmarshal(raw, &rule); err != nil {
+			return nil, err
+		}
+
+		return validateRecurrence(rule)
+	},
+}
+
+// Editable tells whether the clients can change the field.
+func Editable(field string) bool {
+	_, ok := editable[field]
+	return ok
+}
+
+// Removable tells whether the clients can remove the field, a todo always has a text and is done or not.
+func Removable(field string) bool {
+	return Editable(field) && field != FieldText && field != FieldDone
+}
+
+// DecodeField decodes the json value of a field the clients can change into the value stored
+// in the collection, failing with an invalid Error when it isn't valid.
+func DecodeField(field string, raw json.RawMessage) (interface{}, error) {
+	return decodeField(editable, field, raw)
+}
+
+func decodeField(editable map[string]func(json.RawMessage) (interface{}, error), field string, raw json.RawMessage) (interface{}, error) {
+	decode, ok := editable[field]
+	if !ok {
+		return nil, invalidField(field, "the field %q can't be changed", field)
+	}
+
+	if string(raw) == "null" {
+		return nil, invalidField(field, "the field %q can't be null", field)
+	}
+
+	value, err := decode(raw)
+	if _, ok := err.(*messaging.Error); ok {
+		return nil, err
+	} else if err != nil {
+		return nil, invalidField(field, "invalid value for the field %q: %s", field, raw)
+	}
+
+	return value, nil
+}
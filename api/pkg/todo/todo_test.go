@@ -0,0 +1,130 @@
+package todo
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"todo-go/pkg/messaging"
+)
+
+// invalid tells whether err is an invalid Error about the field.
+func invalid(err error, field string) bool {
+	var e *messaging.Error
+	return errors.As(err, &e) && e.Code == messaging.CodeInvalid && e.Details["field"] == field
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		todo      Todo
+		wantField string
+	}{
+		{"text only", Todo{Text: "buy milk"}, ""},
+		{"every field", Todo{Text: "buy milk", Notes: "2l", Priority: PriorityHigh, Tags: []string{"home"}}, ""},
+		{"blank text", Todo{Text: "  "}, FieldText},
+		{"long text", Todo{Text: strings.Repeat("a", MaxTextLength+1)}, FieldText},
+		{"long notes", Todo{Text: "a", Notes: strings.Repeat("a", MaxNotesLength+1)}, FieldNotes},
+		{"unknown priority", Todo{Text: "a", Priority: "whenever"}, FieldPriority},
+		{"blank tag", Todo{Text: "a", Tags: []string{" "}}, FieldTags},
+		{"long tag", Todo{Text: "a", Tags: []string{strings.Repeat("a", MaxTagLength+1)}}, FieldTags},
+		{"duplicate tags count once", Todo{Text: "a", Tags: strings.Split(strings.Repeat("a,", MaxTags)+"b", ",")}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.todo.Validate()
+			if tt.wantField == "" && err != nil {
+				t.Fatalf("Validate() = %v, want no error", err)
+			}
+
+			if tt.wantField != "" && !invalid(err, tt.wantField) {
+				t.Fatalf("Validate() = %v, want an invalid %s", err, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestValidateNormalizes(t *testing.T) {
+	dueAt := time.Date(2024, 3, 1, 10, 0, 0, 123456789, time.FixedZone("CET", 3600))
+	todo := Todo{Text: "buy milk", Tags: []string{" home", "home", "shop "}, DueAt: &dueAt}
+
+	err := todo.Validate()
+	if err != nil {
+		t.Fatalf("Validate() = %v", err)
+	}
+
+	if want := []string{"home", "shop"}; !reflect.DeepEqual(todo.Tags, want) {
+		t.Errorf("Tags = %v, want %v", todo.Tags, want)
+	}
+
+	if want := time.Date(2024, 3, 1, 9, 0, 0, 123000000, time.UTC); !todo.DueAt.Equal(want) || todo.DueAt.Location() != time.UTC {
+		t.Errorf("DueAt = %v, want %v", todo.DueAt, want)
+	}
+
+	tags := make([]string, MaxTags+1)
+	for i := range tags {
+		tags[i] = string(rune('a' + i))
+	}
+
+	todo = Todo{Text: "a", Tags: tags}
+	if err := todo.Validate(); !invalid(err, FieldTags) {
+		t.Errorf("Validate() = %v, want an invalid %s", err, FieldTags)
+	}
+}
+
+func TestDecodeField(t *testing.T) {
+	dueAt := time.Date(2024, 3, 1, 9, 0, 0, 123000000, time.UTC)
+
+	tests := []struct {
+		field     string
+		raw       string
+		want      interface{}
+		wantField string
+	}{
+		{FieldText, `"buy milk"`, "buy milk", ""},
+		{FieldText, `""`, nil, FieldText},
+		{FieldText, `null`, nil, FieldText},
+		{FieldText, `42`, nil, FieldText},
+		{FieldDone, `true`, true, ""},
+		{FieldDone, `"yes"`, nil, FieldDone},
+		{FieldNotes, `"2l"`, "2l", ""},
+		{FieldPriority, `"urgent"`, PriorityUrgent, ""},
+		{FieldPriority, `"soon"`, nil, FieldPriority},
+		{FieldTags, `[" home", "home"]`, []string{"home"}, ""},
+		{FieldTags, `[]`, []string{}, ""},
+		{FieldDueAt, `"2024-03-01T10:00:00.123456+01:00"`, dueAt, ""},
+		{FieldDueAt, `"tomorrow"`, nil, FieldDueAt},
+		{FieldListId, `"inbox"`, "inbox", ""},
+		{FieldListId, `""`, nil, FieldListId},
+		{FieldVersion, `3`, nil, FieldVersion},
+		{FieldOwnerId, `"mallory"`, nil, FieldOwnerId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.field+" "+tt.raw, func(t *testing.T) {
+			got, err := DecodeField(tt.field, []byte(tt.raw))
+			if tt.wantField != "" {
+				if !invalid(err, tt.wantField) {
+					t.Fatalf("DecodeField() = %v, %v, want an invalid %s", got, err, tt.wantField)
+				}
+
+				return
+			}
+
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeField() = %#v, %v, want %#v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemovable(t *testing.T) {
+	for field, want := range map[string]bool{FieldNotes: true, FieldTags: true, FieldText: false, FieldDone: false, FieldVersion: false} {
+		if got := Removable(field); got != want {
+			t.Errorf("Removable(%s) = %v, want %v", field, got, want)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+// Command dead-letters inspects and replays the requests the DAOs gave up on.
+//
+//	dead-letters [-url amqp://...] [-limit n] list <queue>
+//	dead-letters [-url amqp://...] [-limit n] replay <queue>
+//
+// where queue is the DAO queue, e.g. post, not its dead-letter queue. Without -url, the broker
+// is the one of the DEADLETTERS_BROKER_* settings, see config.Broker.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"todo-go/pkg/config"
+	"todo-go/pkg/messaging"
+)
+
+type SvcConfiguration struct {
+	Broker config.Broker
+}
+
+func failOnError(err error, msg string) {
+	if err != nil {
+		log.Fatalf("%s: %s", msg, err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] list|replay <queue>\n", os.Args[0])
+	flag.PrintDefaults()
+}
+
+func main() {
+	url := flag.String("url", "", "message broker connection string, the one of the settings when empty")
+	limit := flag.Int("limit", 100, "maximum number of messages to list or replay")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 2 || *limit <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command, queue := flag.Arg(0), flag.Arg(1)
+
+	var c SvcConfiguration
+	if *url == "" {
+		err := config.Load("deadletters", &c)
+		failOnError(err, "There was a problem loading the settings of the broker")
+
+		*url = c.Broker.URL()
+	}
+
+	conn, err := messaging.DialTLS(*url, c.Broker.TLS.Config())
+	failOnError(err, "Failed to connect to RabbitMQ")
+	defer conn.Close()
+
+	switch command {
+	case "list":
+		letters, err := conn.InspectDeadLetters(queue, *limit)
+		failOnError(err, "Failed to list the dead letters")
+
+		printLetters(letters)
+		fmt.Printf("%d message(s) in %s\n", len(letters), messaging.DeadLetterQueue(queue))
+	case "replay":
+		letters, err := conn.ReplayDeadLetters(queue, *limit)
+		printLetters(letters)
+		failOnError(err, "Failed to replay the dead letters")
+
+		fmt.Printf("%d message(s) replayed to %s\n", len(letters), queue)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func printLetters(letters []messaging.DeadLetter) {
+	for _, letter := range letters {
+		fmt.Printf("%s\t%s\tattempts=%d\tat=%s\terror=%q\tbody=%s\n",
+			letter.CorrelationId, letter.OriginalQueue, letter.Attempts,
+			letter.DeadAt.Format(time.RFC3339), letter.LastError, letter.Body)
+	}
+}
@@ -0,0 +1,7 @@
+module todo-go-dead-letters
+
+go 1.16
+
+require todo-go/pkg v0.0.0
+
+replace todo-go/pkg => ../../pkg